@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kien-tn/chirpy/internal/database"
+)
+
+// Account states. Limited accounts are on probation with reduced quotas,
+// shadowbanned accounts' chirps are only visible to themselves, and suspended
+// accounts can't authenticate.
+const (
+	accountActive       = "active"
+	accountLimited      = "limited"
+	accountShadowbanned = "shadowbanned"
+	accountSuspended    = "suspended"
+)
+
+var accountStates = []string{accountActive, accountLimited, accountShadowbanned, accountSuspended}
+
+const (
+	// limitedChirpsPerDay is how many chirps a limited account can post in
+	// any 24 hours.
+	limitedChirpsPerDay  = 10
+	maxStateReasonLength = 500
+)
+
+// accountState is the user's state right now, treating an expired state as
+// active.
+func accountState(u database.User) string {
+	if u.AccountStateExpiresAt.Valid && !u.AccountStateExpiresAt.Time.After(time.Now()) {
+		return accountActive
+	}
+	return u.AccountState
+}
+
+// quotaExceeded reports whether an account in state that has posted recent
+// chirps in the last 24 hours has used up its daily quota.
+func quotaExceeded(state string, recent int64) bool {
+	return state == accountLimited && recent >= limitedChirpsPerDay
+}
+
+// chirpQuotaExceeded reports whether userID, whose account is in state, can't
+// post another chirp right now.
+func chirpQuotaExceeded(ctx context.Context, q *database.Queries, userID uuid.UUID, state string) (bool, error) {
+	if state != accountLimited {
+		return false, nil
+	}
+	n, err := q.CountChirpsSince(ctx, database.CountChirpsSinceParams{
+		UserID:    userID,
+		CreatedAt: time.Now().Add(-24 * time.Hour),
+	})
+	if err != nil {
+		return false, err
+	}
+	return quotaExceeded(state, n), nil
+}
+
+// allowChirp enforces the daily quota of limited accounts on the
+// authenticated user, both for chirps posted now and ones being scheduled. It
+// writes the error response and returns false when the user is over it.
+func (cfg *apiConfig) allowChirp(w http.ResponseWriter, r *http.Request, userID uuid.UUID) bool {
+	exceeded, err := chirpQuotaExceeded(r.Context(), cfg.db, userID, accountStateFromContext(r.Context()))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error checking chirp quota", err)
+		return false
+	}
+	if exceeded {
+		respondWithError(w, http.StatusTooManyRequests, "Limited accounts can post 10 chirps a day", nil)
+		return false
+	}
+	return true
+}
+
+func suspendedMessage(u database.User) string {
+	if u.AccountStateExpiresAt.Valid {
+		return "Account suspended until " + u.AccountStateExpiresAt.Time.Format(time.RFC3339)
+	}
+	return "Account suspended"
+}
+
+type AccountState struct {
+	UserID    uuid.UUID  `json:"user_id"`
+	State     string     `json:"state"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	Reason    string     `json:"reason"`
+}
+
+// handlerSetAccountState lets a moderator change a user's account state,
+// optionally until expires_at. Suspending an account also signs it out and
+// closes its websocket connections.
+func (cfg *apiConfig) handlerSetAccountState(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		State     string     `json:"state"`
+		ExpiresAt *time.Time `json:"expires_at"`
+		Reason    string     `json:"reason"`
+	}
+	targetID, err := uuid.Parse(r.PathValue("user_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID format", err)
+		return
+	}
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	if !slices.Contains(accountStates, params.State) {
+		respondWithError(w, http.StatusBadRequest, "State must be one of "+strings.Join(accountStates, ", "), nil)
+		return
+	}
+	if params.ExpiresAt != nil && !params.ExpiresAt.After(time.Now()) {
+		respondWithError(w, http.StatusBadRequest, "expires_at must be in the future", nil)
+		return
+	}
+	params.Reason = strings.TrimSpace(params.Reason)
+	if len([]rune(params.Reason)) > maxStateReasonLength {
+		respondWithError(w, http.StatusBadRequest, "Reason must be at most 500 characters", nil)
+		return
+	}
+	expiresAt := sql.NullTime{}
+	if params.ExpiresAt != nil && params.State != accountActive {
+		expiresAt = sql.NullTime{Time: params.ExpiresAt.UTC(), Valid: true}
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating account state", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTracedTx(tx)
+	u, err := qtx.SetAccountState(r.Context(), database.SetAccountStateParams{
+		ID:                    targetID,
+		AccountState:          params.State,
+		AccountStateExpiresAt: expiresAt,
+		AccountStateReason:    params.Reason,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "User not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating account state", err)
+		return
+	}
+	if params.State == accountSuspended {
+		if err := qtx.RevokeUserRefreshTokens(r.Context(), targetID); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error updating account state", err)
+			return
+		}
+	}
+	details := params.State
+	if expiresAt.Valid {
+		details += " until " + expiresAt.Time.Format(time.RFC3339)
+	}
+	if params.Reason != "" {
+		details += ": " + params.Reason
+	}
+	err = recordAudit(r, qtx, auditEntry{
+		Action:     auditAccountState,
+		ActorID:    userIDFromContext(r.Context()),
+		TargetType: auditTargetUser,
+		TargetID:   targetID,
+		Details:    details,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating account state", err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating account state", err)
+		return
+	}
+	if params.State == accountSuspended {
+		cfg.hub.DisconnectUser(targetID)
+	}
+	out := AccountState{UserID: u.ID, State: u.AccountState, Reason: u.AccountStateReason}
+	if u.AccountStateExpiresAt.Valid {
+		out.ExpiresAt = &u.AccountStateExpiresAt.Time
+	}
+	respondWithJSON(w, http.StatusOK, out)
+}
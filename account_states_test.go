@@ -0,0 +1,55 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/kien-tn/chirpy/internal/database"
+)
+
+func TestAccountState(t *testing.T) {
+	past := sql.NullTime{Time: time.Now().Add(-time.Hour), Valid: true}
+	future := sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true}
+	tests := []struct {
+		name      string
+		state     string
+		expiresAt sql.NullTime
+		want      string
+	}{
+		{"active", accountActive, sql.NullTime{}, accountActive},
+		{"permanent suspension", accountSuspended, sql.NullTime{}, accountSuspended},
+		{"suspension in force", accountSuspended, future, accountSuspended},
+		{"expired suspension", accountSuspended, past, accountActive},
+		{"expired limit", accountLimited, past, accountActive},
+		{"shadowban in force", accountShadowbanned, future, accountShadowbanned},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := database.User{AccountState: tt.state, AccountStateExpiresAt: tt.expiresAt}
+			if got := accountState(u); got != tt.want {
+				t.Errorf("accountState = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuotaExceeded(t *testing.T) {
+	tests := []struct {
+		state  string
+		recent int64
+		want   bool
+	}{
+		{accountLimited, 0, false},
+		{accountLimited, limitedChirpsPerDay - 1, false},
+		{accountLimited, limitedChirpsPerDay, true},
+		{accountLimited, limitedChirpsPerDay + 5, true},
+		{accountActive, limitedChirpsPerDay + 5, false},
+		{accountShadowbanned, limitedChirpsPerDay, false},
+	}
+	for _, tt := range tests {
+		if got := quotaExceeded(tt.state, tt.recent); got != tt.want {
+			t.Errorf("quotaExceeded(%q, %d) = %v, want %v", tt.state, tt.recent, got, tt.want)
+		}
+	}
+}
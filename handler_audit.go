@@ -0,0 +1,243 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kien-tn/chirpy/internal/database"
+)
+
+// Audited actions.
+const (
+	auditLogin          = "login"
+	auditTokenRefresh   = "token.refresh"
+	auditTokenRevoke    = "token.revoke"
+	auditPasswordChange = "user.password_change"
+	auditEmailChange    = "user.email_change"
+	auditUpgrade        = "user.upgrade"
+	auditAccountState   = "user.state"
+	auditChirpLabels    = "chirp.labels"
+	auditAdminReset     = "admin.reset"
+	// Moderator actions on reports are recorded as "report." followed by
+	// the moderation action, e.g. "report.assign".
+	auditReportPrefix = "report."
+)
+
+const (
+	auditTargetUser   = "user"
+	auditTargetChirp  = "chirp"
+	auditTargetReport = "report"
+)
+
+const (
+	auditSuccess = "success"
+	auditFailure = "failure"
+)
+
+const auditExportPageSize = 500
+
+// auditEntry is a security-sensitive event. ActorID is uuid.Nil when nobody
+// is signed in, and TargetID when the action has no target.
+type auditEntry struct {
+	Action     string
+	Failed     bool
+	ActorID    uuid.UUID
+	TargetType string
+	TargetID   uuid.UUID
+	Details    string
+}
+
+// recordAudit writes e along with where the request came from. Pass a
+// transaction's queries to record the entry only if the action commits.
+func recordAudit(r *http.Request, q *database.Queries, e auditEntry) error {
+	outcome := auditSuccess
+	if e.Failed {
+		outcome = auditFailure
+	}
+	return q.CreateAuditEntry(r.Context(), database.CreateAuditEntryParams{
+		Action:     e.Action,
+		Outcome:    outcome,
+		ActorID:    uuid.NullUUID{UUID: e.ActorID, Valid: e.ActorID != uuid.Nil},
+		TargetType: e.TargetType,
+		TargetID:   uuid.NullUUID{UUID: e.TargetID, Valid: e.TargetID != uuid.Nil},
+		Ip:         clientIP(r),
+		UserAgent:  r.UserAgent(),
+		RequestID:  requestIDFromContext(r.Context()),
+		Details:    e.Details,
+	})
+}
+
+// auditEmail stands in for an email address in audit details. The log is
+// append-only and outlives account deletion, so it holds a keyed hash that
+// still matches repeated attempts with the same address.
+func (cfg *apiConfig) auditEmail(email string) string {
+	mac := hmac.New(sha256.New, []byte(cfg.secretKey))
+	mac.Write([]byte("audit-email:" + strings.ToLower(strings.TrimSpace(email))))
+	return "email:" + hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// audit records e outside of any transaction. A failure to write the entry is
+// logged rather than failing the request.
+func (cfg *apiConfig) audit(r *http.Request, e auditEntry) {
+	if err := recordAudit(r, cfg.db, e); err != nil {
+		loggerFromContext(r.Context()).Error("Error writing audit entry", "action", e.Action, "error", err)
+	}
+}
+
+// clientIP returns the address the request came from.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+type AuditEntry struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Action     string     `json:"action"`
+	Outcome    string     `json:"outcome"`
+	ActorID    *uuid.UUID `json:"actor_id"`
+	TargetType string     `json:"target_type"`
+	TargetID   *uuid.UUID `json:"target_id"`
+	IP         string     `json:"ip"`
+	UserAgent  string     `json:"user_agent"`
+	RequestID  string     `json:"request_id"`
+	Details    string     `json:"details"`
+}
+
+func auditEntryResponse(e database.AuditLog) AuditEntry {
+	return AuditEntry{
+		ID:         e.ID,
+		CreatedAt:  e.CreatedAt,
+		Action:     e.Action,
+		Outcome:    e.Outcome,
+		ActorID:    nullableUUID(e.ActorID),
+		TargetType: e.TargetType,
+		TargetID:   nullableUUID(e.TargetID),
+		IP:         e.Ip,
+		UserAgent:  e.UserAgent,
+		RequestID:  e.RequestID,
+		Details:    e.Details,
+	}
+}
+
+// auditFilters reads the action, outcome, actor_id, target_id, since and
+// until query parameters. since and until are RFC 3339 times.
+func auditFilters(r *http.Request) (database.ListAuditEntriesParams, error) {
+	q := r.URL.Query()
+	params := database.ListAuditEntriesParams{
+		Action:  q.Get("action"),
+		Outcome: q.Get("outcome"),
+		Until:   firstPage.Time,
+	}
+	if params.Outcome != "" && params.Outcome != auditSuccess && params.Outcome != auditFailure {
+		return params, fmt.Errorf("outcome must be success or failure")
+	}
+	for name, id := range map[string]*uuid.UUID{"actor_id": &params.ActorID, "target_id": &params.TargetID} {
+		if s := q.Get(name); s != "" {
+			parsed, err := uuid.Parse(s)
+			if err != nil {
+				return params, fmt.Errorf("invalid %s format", name)
+			}
+			*id = parsed
+		}
+	}
+	for name, t := range map[string]*time.Time{"since": &params.Since, "until": &params.Until} {
+		if s := q.Get(name); s != "" {
+			parsed, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return params, fmt.Errorf("%s must be an RFC 3339 time", name)
+			}
+			*t = parsed.UTC()
+		}
+	}
+	return params, nil
+}
+
+// handlerGetAuditLog lists audit entries newest first, filtered as described
+// in auditFilters.
+func (cfg *apiConfig) handlerGetAuditLog(w http.ResponseWriter, r *http.Request) {
+	cursor, limit, err := pageParams(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	params, err := auditFilters(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	params.CursorTime = cursor.Time
+	params.CursorID = cursor.ID
+	params.PageSize = limit
+	rows, err := cfg.db.ListAuditEntries(r.Context(), params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting audit log", err)
+		return
+	}
+	output := []AuditEntry{}
+	for _, e := range rows {
+		output = append(output, auditEntryResponse(e))
+	}
+	nextCursor := ""
+	if len(rows) == int(limit) {
+		last := rows[len(rows)-1]
+		nextCursor = encodeCursor(last.CreatedAt, last.ID)
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"entries":     output,
+		"next_cursor": nextCursor,
+	})
+}
+
+// handlerExportAuditLog streams every matching audit entry, newest first, as
+// JSON Lines.
+func (cfg *apiConfig) handlerExportAuditLog(w http.ResponseWriter, r *http.Request) {
+	params, err := auditFilters(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	params.CursorTime = firstPage.Time
+	params.CursorID = firstPage.ID
+	params.PageSize = auditExportPageSize
+	rows, err := cfg.db.ListAuditEntries(r.Context(), params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error exporting audit log", err)
+		return
+	}
+	extendWriteDeadline(w)
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="audit-log.jsonl"`)
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	for {
+		for _, e := range rows {
+			if err := enc.Encode(auditEntryResponse(e)); err != nil {
+				return
+			}
+		}
+		if len(rows) < auditExportPageSize {
+			return
+		}
+		last := rows[len(rows)-1]
+		params.CursorTime = last.CreatedAt
+		params.CursorID = last.ID
+		// The response has started, so a failure can only cut it short.
+		rows, err = cfg.db.ListAuditEntries(r.Context(), params)
+		if err != nil {
+			loggerFromContext(r.Context()).Error("Error exporting audit log", "error", err)
+			return
+		}
+	}
+}
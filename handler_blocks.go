@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kien-tn/chirpy/internal/database"
+)
+
+type Relationship struct {
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// targetUserID parses the {user_id} path value and rejects acting on yourself.
+func targetUserID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	targetID, err := uuid.Parse(r.PathValue("user_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID format", err)
+		return uuid.Nil, false
+	}
+	if targetID == userIDFromContext(r.Context()) {
+		respondWithError(w, http.StatusBadRequest, "You can't do that to yourself", nil)
+		return uuid.Nil, false
+	}
+	return targetID, true
+}
+
+func (cfg *apiConfig) handlerBlockUser(w http.ResponseWriter, r *http.Request) {
+	targetID, ok := targetUserID(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.db.CreateBlock(r.Context(), database.CreateBlockParams{
+		BlockerID: userIDFromContext(r.Context()),
+		BlockedID: targetID,
+	})
+	if isForeignKeyViolation(err) {
+		respondWithError(w, http.StatusNotFound, "User not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error blocking user", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerUnblockUser(w http.ResponseWriter, r *http.Request) {
+	targetID, ok := targetUserID(w, r)
+	if !ok {
+		return
+	}
+	n, err := cfg.db.DeleteBlock(r.Context(), database.DeleteBlockParams{
+		BlockerID: userIDFromContext(r.Context()),
+		BlockedID: targetID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error unblocking user", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, "User is not blocked", nil)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerGetBlocks(w http.ResponseWriter, r *http.Request) {
+	blocks, err := cfg.db.GetBlocksByBlocker(r.Context(), userIDFromContext(r.Context()))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting blocks", err)
+		return
+	}
+	output := []Relationship{}
+	for _, b := range blocks {
+		output = append(output, Relationship{UserID: b.BlockedID, CreatedAt: b.CreatedAt})
+	}
+	respondWithJSON(w, http.StatusOK, output)
+}
+
+func (cfg *apiConfig) handlerMuteUser(w http.ResponseWriter, r *http.Request) {
+	targetID, ok := targetUserID(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.db.CreateMute(r.Context(), database.CreateMuteParams{
+		MuterID: userIDFromContext(r.Context()),
+		MutedID: targetID,
+	})
+	if isForeignKeyViolation(err) {
+		respondWithError(w, http.StatusNotFound, "User not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error muting user", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerUnmuteUser(w http.ResponseWriter, r *http.Request) {
+	targetID, ok := targetUserID(w, r)
+	if !ok {
+		return
+	}
+	n, err := cfg.db.DeleteMute(r.Context(), database.DeleteMuteParams{
+		MuterID: userIDFromContext(r.Context()),
+		MutedID: targetID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error unmuting user", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, "User is not muted", nil)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerGetMutes(w http.ResponseWriter, r *http.Request) {
+	mutes, err := cfg.db.GetMutesByMuter(r.Context(), userIDFromContext(r.Context()))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting mutes", err)
+		return
+	}
+	output := []Relationship{}
+	for _, m := range mutes {
+		output = append(output, Relationship{UserID: m.MutedID, CreatedAt: m.CreatedAt})
+	}
+	respondWithJSON(w, http.StatusOK, output)
+}
+
+// hiddenAuthors returns the users whose chirps the viewer shouldn't see in
+// listings: those who blocked the viewer and those the viewer muted.
+func (cfg *apiConfig) hiddenAuthors(ctx context.Context, viewerID uuid.UUID) (map[uuid.UUID]bool, error) {
+	hidden := map[uuid.UUID]bool{}
+	if viewerID == uuid.Nil {
+		return hidden, nil
+	}
+	blockers, err := cfg.db.GetBlockerIDs(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	muted, err := cfg.db.GetMutedIDs(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range append(blockers, muted...) {
+		hidden[id] = true
+	}
+	return hidden, nil
+}
+
+// hiddenFrom is the reverse of hiddenAuthors: the users who shouldn't see
+// authorID's chirps in their live timelines.
+func (cfg *apiConfig) hiddenFrom(ctx context.Context, authorID uuid.UUID) ([]uuid.UUID, error) {
+	blocked, err := cfg.db.GetBlockedIDs(ctx, authorID)
+	if err != nil {
+		return nil, err
+	}
+	muters, err := cfg.db.GetMuterIDs(ctx, authorID)
+	if err != nil {
+		return nil, err
+	}
+	return append(blocked, muters...), nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/kien-tn/chirpy/internal/database"
+)
+
+func (cfg *apiConfig) handlerBookmarkChirp(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirp_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID format", err)
+		return
+	}
+	userID := userIDFromContext(r.Context())
+	c, err := cfg.db.GetVisibleChirpByID(r.Context(), database.GetVisibleChirpByIDParams{
+		ID:       chirpID,
+		ViewerID: userID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error bookmarking chirp", err)
+		return
+	}
+	blockers, err := cfg.db.GetBlockerIDs(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error bookmarking chirp", err)
+		return
+	}
+	if slices.Contains(blockers, c.UserID) {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", nil)
+		return
+	}
+	err = cfg.db.CreateBookmark(r.Context(), database.CreateBookmarkParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error bookmarking chirp", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerUnbookmarkChirp(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirp_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID format", err)
+		return
+	}
+	n, err := cfg.db.DeleteBookmark(r.Context(), database.DeleteBookmarkParams{
+		UserID:  userIDFromContext(r.Context()),
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error removing bookmark", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, "Chirp is not bookmarked", nil)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerGetBookmarks lists the caller's bookmarks, most recently saved
+// first. Chirps that were deleted or whose author has since blocked the
+// caller are left out.
+func (cfg *apiConfig) handlerGetBookmarks(w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(r.Context())
+	cursor, limit, err := pageParams(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	rows, err := cfg.db.ListBookmarkedChirps(r.Context(), database.ListBookmarkedChirpsParams{
+		UserID:     userID,
+		CursorTime: cursor.Time,
+		CursorID:   cursor.ID,
+		PageSize:   limit,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting bookmarks", err)
+		return
+	}
+	chirps := make([]database.Chirp, 0, len(rows))
+	for _, row := range rows {
+		chirps = append(chirps, database.Chirp{
+			ID:             row.ID,
+			CreatedAt:      row.CreatedAt,
+			UpdatedAt:      row.UpdatedAt,
+			Body:           row.Body,
+			UserID:         row.UserID,
+			DeletedAt:      row.DeletedAt,
+			QuotedChirpID:  row.QuotedChirpID,
+			ContentWarning: row.ContentWarning,
+			SensitiveMedia: row.SensitiveMedia,
+			RemovedAt:      row.RemovedAt,
+			BodyHash:       row.BodyHash,
+			Hold:           row.Hold,
+			HeldUntil:      row.HeldUntil,
+		})
+	}
+	output, err := cfg.chirpResponses(r.Context(), chirps, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting bookmarks", err)
+		return
+	}
+	nextCursor := ""
+	if len(rows) == int(limit) {
+		last := rows[len(rows)-1]
+		nextCursor = encodeCursor(last.BookmarkedAt, last.ID)
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"chirps":      output,
+		"next_cursor": nextCursor,
+	})
+}
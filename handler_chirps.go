@@ -1,28 +1,334 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
-	"log"
+	"errors"
 	"net/http"
+	"slices"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/kien-tn/chirpy/internal/auth"
 	"github.com/kien-tn/chirpy/internal/database"
+	"github.com/kien-tn/chirpy/internal/realtime"
+	"github.com/kien-tn/chirpy/internal/spam"
+	"github.com/kien-tn/chirpy/internal/unfurl"
+)
+
+const (
+	maxChirpLength          = 140
+	maxContentWarningLength = 100
 )
 
 type Chirp struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	UserID    uuid.UUID `json:"user_id"`
-	Body      string    `json:"body"`
+	ID           uuid.UUID     `json:"id"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+	UserID       uuid.UUID     `json:"user_id"`
+	Body         string        `json:"body"`
+	Author       ChirpAuthor   `json:"author"`
+	Media        []ChirpMedia  `json:"media"`
+	LinkPreviews []LinkPreview `json:"link_previews"`
+	Poll         *ChirpPoll    `json:"poll"`
+	Pinned       bool          `json:"pinned"`
+	QuotedChirp  *QuotedChirp  `json:"quoted_chirp"`
+	// ContentWarning and SensitiveMedia are set by the author or a
+	// moderator. Collapsed tells the client to hide the body and media
+	// behind the warning, following the viewer's preference.
+	ContentWarning string `json:"content_warning"`
+	SensitiveMedia bool   `json:"sensitive_media"`
+	Collapsed      bool   `json:"collapsed"`
+	// Hold is "delay" or "review" while the spam filter keeps the chirp
+	// from everyone but its author. Delayed chirps publish at HeldUntil.
+	Hold      string     `json:"hold"`
+	HeldUntil *time.Time `json:"held_until"`
+}
+
+type ChirpAuthor struct {
+	Handle      string `json:"handle"`
+	DisplayName string `json:"display_name"`
+}
+
+// chirpResponses converts chirps to their API representation, embedding each
+// author's profile so clients don't need a lookup per chirp. viewerID is
+// uuid.Nil for anonymous viewers and broadcasts.
+func (cfg *apiConfig) chirpResponses(ctx context.Context, chirps []database.Chirp, viewerID uuid.UUID) ([]Chirp, error) {
+	originals, err := cfg.quotedOriginals(ctx, chirps, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	authorIDs := []uuid.UUID{}
+	chirpIDs := make([]uuid.UUID, 0, len(chirps))
+	for _, c := range chirps {
+		if !slices.Contains(authorIDs, c.UserID) {
+			authorIDs = append(authorIDs, c.UserID)
+		}
+		chirpIDs = append(chirpIDs, c.ID)
+	}
+	for _, c := range originals {
+		if !slices.Contains(authorIDs, c.UserID) {
+			authorIDs = append(authorIDs, c.UserID)
+		}
+	}
+	authors := map[uuid.UUID]ChirpAuthor{}
+	if len(authorIDs) > 0 {
+		users, err := cfg.db.GetUsersByIDs(ctx, authorIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range users {
+			authors[u.ID] = ChirpAuthor{
+				Handle:      u.Handle.String,
+				DisplayName: u.DisplayName,
+			}
+		}
+	}
+	attachments, err := cfg.chirpMedia(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	previews, err := cfg.chirpLinkPreviews(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	polls, err := cfg.chirpPolls(ctx, chirpIDs, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	pinned, err := cfg.db.GetPinnedChirpIDs(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	expandSensitive := false
+	if viewerID != uuid.Nil {
+		viewer, err := cfg.db.GetUserByID(ctx, viewerID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		expandSensitive = viewer.ExpandSensitiveContent
+	}
+	output := make([]Chirp, 0, len(chirps))
+	for _, c := range chirps {
+		chirpMedia := attachments[c.ID]
+		if chirpMedia == nil {
+			chirpMedia = []ChirpMedia{}
+		}
+		linkPreviews := previews[c.ID]
+		if linkPreviews == nil {
+			linkPreviews = []LinkPreview{}
+		}
+		chirp := Chirp{
+			ID:             c.ID,
+			CreatedAt:      c.CreatedAt,
+			UpdatedAt:      c.UpdatedAt,
+			Body:           c.Body,
+			UserID:         c.UserID,
+			Author:         authors[c.UserID],
+			Media:          chirpMedia,
+			LinkPreviews:   linkPreviews,
+			Poll:           polls[c.ID],
+			Pinned:         slices.Contains(pinned, c.ID),
+			QuotedChirp:    quotedChirp(c, originals, authors),
+			ContentWarning: c.ContentWarning,
+			SensitiveMedia: c.SensitiveMedia,
+			Collapsed:      !expandSensitive && (c.ContentWarning != "" || c.SensitiveMedia),
+			Hold:           c.Hold,
+		}
+		if c.HeldUntil.Valid {
+			chirp.HeldUntil = &c.HeldUntil.Time
+		}
+		output = append(output, chirp)
+	}
+	return output, nil
+}
+
+func (cfg *apiConfig) chirpResponse(ctx context.Context, c database.Chirp, viewerID uuid.UUID) (Chirp, error) {
+	output, err := cfg.chirpResponses(ctx, []database.Chirp{c}, viewerID)
+	if err != nil {
+		return Chirp{}, err
+	}
+	return output[0], nil
+}
+
+// invalidMediaError reports a media ID that isn't one of the author's own
+// unattached uploads.
+type invalidMediaError struct {
+	id uuid.UUID
+}
+
+func (e invalidMediaError) Error() string {
+	return "invalid media ID: " + e.id.String()
+}
+
+// newChirp is a validated chirp ready to be inserted.
+type newChirp struct {
+	UserID         uuid.UUID
+	Body           string
+	MediaIDs       []uuid.UUID
+	QuotedChirpID  uuid.NullUUID
+	ContentWarning string
+	SensitiveMedia bool
+	// BodyHash, Hold, HeldUntil and Spam are filled in by screenChirp.
+	BodyHash  string
+	Hold      string
+	HeldUntil sql.NullTime
+	Spam      spam.Verdict
+}
+
+// insertChirp creates a chirp along with its media and links inside an open
+// transaction. It returns the links so they can be unfurled after commit.
+func insertChirp(ctx context.Context, qtx *database.Queries, n newChirp) (database.Chirp, []string, error) {
+	c, err := qtx.CreateChirp(ctx, database.CreateChirpParams{
+		Body:           n.Body,
+		UserID:         n.UserID,
+		QuotedChirpID:  n.QuotedChirpID,
+		ContentWarning: n.ContentWarning,
+		SensitiveMedia: n.SensitiveMedia,
+		BodyHash:       n.BodyHash,
+		Hold:           n.Hold,
+		HeldUntil:      n.HeldUntil,
+	})
+	if err != nil {
+		return database.Chirp{}, nil, err
+	}
+	if c.Hold == holdReview {
+		if err := queueForReview(ctx, qtx, c, n.Spam); err != nil {
+			return database.Chirp{}, nil, err
+		}
+	}
+	for i, mediaID := range n.MediaIDs {
+		// Only the uploader's own, not yet attached, media can be used.
+		attached, err := qtx.AttachMediaToChirp(ctx, database.AttachMediaToChirpParams{
+			ChirpID:  uuid.NullUUID{UUID: c.ID, Valid: true},
+			Position: int32(i),
+			ID:       mediaID,
+			UserID:   n.UserID,
+		})
+		if err != nil {
+			return database.Chirp{}, nil, err
+		}
+		if attached == 0 {
+			return database.Chirp{}, nil, invalidMediaError{id: mediaID}
+		}
+	}
+	links := unfurl.ExtractURLs(c.Body)
+	if len(links) > maxChirpLinks {
+		links = links[:maxChirpLinks]
+	}
+	for i, link := range links {
+		err := qtx.AddChirpLink(ctx, database.AddChirpLinkParams{
+			ChirpID:  c.ID,
+			Url:      link,
+			Position: int32(i),
+		})
+		if err != nil {
+			return database.Chirp{}, nil, err
+		}
+	}
+	return c, links, nil
+}
+
+// announceChirp runs everything that follows a committed chirp: link
+// previews, mention notifications and the realtime fan-out.
+func (cfg *apiConfig) announceChirp(ctx context.Context, c database.Chirp, links []string) (Chirp, error) {
+	// Previews that are already cached are included right away; the rest
+	// are pushed to the chirp's thread topic once fetched.
+	for _, link := range links {
+		cfg.previews.Enqueue(c.ID, link)
+	}
+	chirp, err := cfg.chirpResponse(ctx, c, uuid.Nil)
+	if err != nil {
+		return Chirp{}, err
+	}
+	author, err := cfg.db.GetUserByID(ctx, c.UserID)
+	if err != nil {
+		return Chirp{}, err
+	}
+	if accountState(author) == accountShadowbanned {
+		// Only the author can see the chirp, so nobody else hears about it.
+		cfg.hub.PublishToUser(c.UserID, realtime.TopicTimeline, "chirp.created", chirp)
+		cfg.hub.PublishToUser(c.UserID, realtime.TimelineTopic(c.UserID), "chirp.created", chirp)
+		return chirp, nil
+	}
+	if err := cfg.notifyMentions(ctx, c); err != nil {
+		loggerFromContext(ctx).Error("Error sending mention notifications", "chirp_id", c.ID, "error", err)
+	}
+	hiddenFrom, err := cfg.hiddenFrom(ctx, c.UserID)
+	if err != nil {
+		loggerFromContext(ctx).Error("Error getting blocks and mutes", "user_id", c.UserID, "error", err)
+	}
+	// The broadcast embeds the original of a quote, so the people its author
+	// blocked or who muted its author get the quote with a placeholder.
+	var placeholderFor []uuid.UUID
+	if q := chirp.QuotedChirp; q != nil && q.Available {
+		if err := cfg.notifyQuote(ctx, c, q); err != nil {
+			loggerFromContext(ctx).Error("Error sending quote notification", "chirp_id", c.ID, "error", err)
+		}
+		hiddenFromOriginal, err := cfg.hiddenFrom(ctx, *q.UserID)
+		if err != nil {
+			loggerFromContext(ctx).Error("Error getting blocks and mutes", "user_id", *q.UserID, "error", err)
+		}
+		for _, id := range hiddenFromOriginal {
+			if !slices.Contains(hiddenFrom, id) {
+				placeholderFor = append(placeholderFor, id)
+			}
+		}
+	}
+	excluded := append(slices.Clone(hiddenFrom), placeholderFor...)
+	placeholder := chirp
+	if chirp.QuotedChirp != nil {
+		placeholder.QuotedChirp = &QuotedChirp{ID: chirp.QuotedChirp.ID}
+	}
+	for _, topic := range []string{realtime.TopicTimeline, realtime.TimelineTopic(c.UserID)} {
+		cfg.hub.PublishExcluding(topic, "chirp.created", chirp, excluded)
+		cfg.hub.PublishToUsers(placeholderFor, topic, "chirp.created", placeholder)
+	}
+	return chirp, nil
+}
+
+// publishChirpEvent sends an event about an existing chirp on topics to the
+// people who can see the chirp: nobody while it's held, only its author while
+// they're shadowbanned, and otherwise everyone except the users hidden from
+// its author.
+func (cfg *apiConfig) publishChirpEvent(ctx context.Context, c database.Chirp, event string, data any, topics ...string) error {
+	// A held chirp is announced when it's released.
+	if c.Hold != "" {
+		return nil
+	}
+	author, err := cfg.db.GetUserByID(ctx, c.UserID)
+	if err != nil {
+		return err
+	}
+	if accountState(author) == accountShadowbanned {
+		for _, topic := range topics {
+			cfg.hub.PublishToUser(c.UserID, topic, event, data)
+		}
+		return nil
+	}
+	hiddenFrom, err := cfg.hiddenFrom(ctx, c.UserID)
+	if err != nil {
+		return err
+	}
+	for _, topic := range topics {
+		cfg.hub.PublishExcluding(topic, event, data, hiddenFrom)
+	}
+	return nil
 }
 
 func (cfg *apiConfig) handlerCreateChip(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
-		Body string `json:"body"`
+		Body      string      `json:"body"`
+		MediaIDs  []uuid.UUID `json:"media_ids"`
+		PublishAt *time.Time  `json:"publish_at"`
+		Poll      *pollParams `json:"poll"`
+		// QuotedChirpID makes this a quote of another chirp.
+		QuotedChirpID  *uuid.UUID `json:"quoted_chirp_id"`
+		ContentWarning string     `json:"content_warning"`
+		SensitiveMedia bool       `json:"sensitive_media"`
 	}
 
 	decoder := json.NewDecoder(r.Body)
@@ -32,7 +338,7 @@ func (cfg *apiConfig) handlerCreateChip(w http.ResponseWriter, r *http.Request)
 		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
 		return
 	}
-	if len(params.Body) > 140 {
+	if len(params.Body) > maxChirpLength {
 		// If the body is too long, return a 400 Bad Request
 		respondWithError(w, http.StatusBadRequest, "Chirp is too long", nil)
 		return
@@ -45,50 +351,172 @@ func (cfg *apiConfig) handlerCreateChip(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	c, err := cfg.db.CreateChirp(r.Context(), database.CreateChirpParams{
-		Body:   params.Body,
-		UserID: userID,
-	})
+	if len(params.MediaIDs) > maxChirpMedia {
+		respondWithError(w, http.StatusBadRequest, "A chirp can have at most 4 media attachments", nil)
+		return
+	}
+
+	params.ContentWarning = strings.TrimSpace(params.ContentWarning)
+	if len([]rune(params.ContentWarning)) > maxContentWarningLength {
+		respondWithError(w, http.StatusBadRequest, "Content warning must be at most 100 characters", nil)
+		return
+	}
 
+	if params.Poll != nil {
+		opensAt := time.Now()
+		if params.PublishAt != nil {
+			opensAt = *params.PublishAt
+		}
+		if msg := validatePoll(*params.Poll, opensAt); msg != "" {
+			respondWithError(w, http.StatusBadRequest, msg, nil)
+			return
+		}
+	}
+
+	// A chirp with a publish time is stored as a scheduled draft instead.
+	if params.PublishAt != nil {
+		cfg.createDraft(w, r, userID, draftParams{
+			Body:           params.Body,
+			MediaIDs:       params.MediaIDs,
+			PublishAt:      params.PublishAt,
+			Poll:           params.Poll,
+			QuotedChirpID:  params.QuotedChirpID,
+			ContentWarning: params.ContentWarning,
+			SensitiveMedia: params.SensitiveMedia,
+		})
+		return
+	}
+
+	if !cfg.allowChirp(w, r, userID) {
+		return
+	}
+
+	if params.QuotedChirpID != nil {
+		ok, err := cfg.canQuote(r.Context(), userID, *params.QuotedChirpID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error creating chirp", err)
+			return
+		}
+		if !ok {
+			respondWithError(w, http.StatusBadRequest, "Invalid quoted chirp ID", nil)
+			return
+		}
+	}
+
+	n := newChirp{
+		UserID:         userID,
+		Body:           params.Body,
+		MediaIDs:       params.MediaIDs,
+		QuotedChirpID:  quotedChirpParam(params.QuotedChirpID),
+		ContentWarning: params.ContentWarning,
+		SensitiveMedia: params.SensitiveMedia,
+	}
+	verdict, err := cfg.screenChirp(r.Context(), &n, false)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error creating chirp", err)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"id":         c.ID,
-		"created_at": c.CreatedAt,
-		"updated_at": c.UpdatedAt,
-		"body":       c.Body,
-		"user_id":    c.UserID,
-	})
+	if verdict.Action == spam.Reject {
+		respondWithError(w, http.StatusBadRequest, "Chirp was rejected as spam", nil)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating chirp", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTracedTx(tx)
+	c, links, err := insertChirp(r.Context(), qtx, n)
+	var mediaErr invalidMediaError
+	if errors.As(err, &mediaErr) {
+		respondWithError(w, http.StatusBadRequest, "Invalid media ID: "+mediaErr.id.String(), nil)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating chirp", err)
+		return
+	}
+	if params.Poll != nil {
+		if err := insertPoll(r.Context(), qtx, c.ID, *params.Poll); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error creating poll", err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating chirp", err)
+		return
+	}
+	cfg.metrics.chirpCreated(chirpSourceAPI)
+	// A held chirp is announced when it's released.
+	if c.Hold != "" {
+		chirp, err := cfg.chirpResponse(r.Context(), c, userID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error getting chirp", err)
+			return
+		}
+		respondWithJSON(w, http.StatusAccepted, chirp)
+		return
+	}
+	chirp, err := cfg.announceChirp(r.Context(), c, links)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting chirp author", err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, chirp)
 }
 
 func (cfg *apiConfig) handlerGetAllChirps(w http.ResponseWriter, r *http.Request) {
-	output := []Chirp{}
 	var chirps []database.Chirp
+	var pinnedIDs []uuid.UUID
 	var err error
+	viewerID := cfg.viewerID(r)
 	s := r.URL.Query().Get("author_id")
-	log.Println("author_id found in request path: ", s)
+	loggerFromContext(r.Context()).Debug("author_id found in request query", "author_id", s)
+	authorID := uuid.Nil
 	if s != "" {
-		authorID, err := uuid.Parse(s)
+		authorID, err = uuid.Parse(s)
 		if err != nil {
 			respondWithError(w, http.StatusBadRequest, "Invalid author ID format", err)
 			return
 		}
-		chirps, err = cfg.db.GetChirpsByUserID(r.Context(), authorID)
+	}
+	// Without a cursor or limit every chirp is returned as a plain array, as
+	// before the listing was paginated.
+	if q := r.URL.Query(); q.Has("cursor") || q.Has("limit") {
+		cfg.getChirpsPage(w, r, authorID, viewerID)
+		return
+	}
+	if authorID != uuid.Nil {
+		chirps, err = cfg.db.GetChirpsByUserID(r.Context(), database.GetChirpsByUserIDParams{
+			UserID:   authorID,
+			ViewerID: viewerID,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error getting chirps", err)
+			return
+		}
+		pinnedIDs, err = cfg.db.GetPinnedChirpIDsByUserID(r.Context(), authorID)
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, "Error getting chirps", err)
 			return
 		}
 	} else {
-		chirps, err = cfg.db.GetAllChirps(r.Context())
+		chirps, err = cfg.db.GetAllChirps(r.Context(), viewerID)
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, "Error getting chirps", err)
 			return
 		}
 	}
+	hidden, err := cfg.hiddenAuthors(r.Context(), viewerID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting chirps", err)
+		return
+	}
+	chirps = slices.DeleteFunc(chirps, func(c database.Chirp) bool {
+		return hidden[c.UserID]
+	})
 	sortQuery := r.URL.Query().Get("sort")
 	if sortQuery == "desc" {
 		sort.Slice(chirps, func(i, j int) bool {
@@ -99,43 +527,107 @@ func (cfg *apiConfig) handlerGetAllChirps(w http.ResponseWriter, r *http.Request
 			return chirps[i].CreatedAt.Before(chirps[j].CreatedAt)
 		})
 	}
-	for _, c := range chirps {
-		output = append(output, Chirp{
-			ID:        c.ID,
-			CreatedAt: c.CreatedAt,
-			UpdatedAt: c.UpdatedAt,
-			Body:      c.Body,
-			UserID:    c.UserID,
-		})
+	// A profile shows its pinned chirps first.
+	chirps = pinnedFirst(chirps, pinnedIDs)
+	output, err := cfg.chirpResponses(r.Context(), chirps, viewerID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting chirps", err)
+		return
 	}
 	respondWithJSON(w, http.StatusOK, output)
 }
 
+// getChirpsPage lists chirps newest first, a page at a time. When listing a
+// single author, the first page leads with their pinned chirps and later
+// pages leave them out.
+func (cfg *apiConfig) getChirpsPage(w http.ResponseWriter, r *http.Request, authorID, viewerID uuid.UUID) {
+	if r.URL.Query().Get("sort") == "asc" {
+		respondWithError(w, http.StatusBadRequest, "Paginated chirps are listed newest first", nil)
+		return
+	}
+	cursor, limit, err := pageParams(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	chirps, err := cfg.db.ListChirps(r.Context(), database.ListChirpsParams{
+		AuthorID:   authorID,
+		ViewerID:   viewerID,
+		CursorTime: cursor.Time,
+		CursorID:   cursor.ID,
+		PageSize:   limit,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting chirps", err)
+		return
+	}
+	nextCursor := ""
+	if len(chirps) == int(limit) {
+		last := chirps[len(chirps)-1]
+		nextCursor = encodeCursor(last.CreatedAt, last.ID)
+	}
+	if authorID != uuid.Nil && cursor == firstPage {
+		pinned, err := cfg.db.ListPinnedChirps(r.Context(), database.ListPinnedChirpsParams{
+			AuthorID: authorID,
+			ViewerID: viewerID,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error getting chirps", err)
+			return
+		}
+		chirps = append(pinned, chirps...)
+	}
+	output, err := cfg.chirpResponses(r.Context(), chirps, viewerID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting chirps", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"chirps":      output,
+		"next_cursor": nextCursor,
+	})
+}
+
 func (cfg *apiConfig) handlerGetChirpById(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("chirp_id")
-	log.Println("chirp ID found in request path: ", id)
+	loggerFromContext(r.Context()).Debug("chirp ID found in request path", "chirp_id", id)
 	chirpID, err := uuid.Parse(id)
 	if err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID format", err)
 		return
 	}
-	c, err := cfg.db.GetChirpByID(r.Context(), chirpID)
+	viewerID := cfg.viewerID(r)
+	c, err := cfg.db.GetVisibleChirpByID(r.Context(), database.GetVisibleChirpByIDParams{
+		ID:       chirpID,
+		ViewerID: viewerID,
+	})
 	if err != nil {
 		respondWithError(w, http.StatusNotFound, "Error getting chirp", err)
 		return
 	}
-	respondWithJSON(w, http.StatusOK, Chirp{
-		ID:        c.ID,
-		CreatedAt: c.CreatedAt,
-		UpdatedAt: c.UpdatedAt,
-		Body:      c.Body,
-		UserID:    c.UserID,
-	})
+	// Blocked users can't see the blocker's chirps at all.
+	if viewerID != uuid.Nil {
+		blockers, err := cfg.db.GetBlockerIDs(r.Context(), viewerID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error getting chirp", err)
+			return
+		}
+		if slices.Contains(blockers, c.UserID) {
+			respondWithError(w, http.StatusNotFound, "Error getting chirp", nil)
+			return
+		}
+	}
+	chirp, err := cfg.chirpResponse(r.Context(), c, viewerID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting chirp", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, chirp)
 }
 
 func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("chirp_id")
-	log.Println("chirp ID found in request path: ", id)
+	loggerFromContext(r.Context()).Debug("chirp ID found in request path", "chirp_id", id)
 	chirpID, err := uuid.Parse(id)
 	if err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID format", err)
@@ -169,5 +661,19 @@ func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request)
 		respondWithError(w, http.StatusInternalServerError, "Error deleting chirp", err)
 		return
 	}
+	// A deleted chirp gives up its pin rather than holding a slot while in
+	// the trash.
+	_, err = cfg.db.UnpinChirp(r.Context(), database.UnpinChirpParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error deleting chirp", err)
+		return
+	}
+	deleted := map[string]interface{}{"id": chirpID}
+	cfg.hub.Publish(realtime.TopicTimeline, "chirp.deleted", deleted)
+	cfg.hub.Publish(realtime.TimelineTopic(userID), "chirp.deleted", deleted)
+	cfg.hub.Publish(realtime.ThreadTopic(chirpID), "chirp.deleted", deleted)
 	respondWithJSON(w, http.StatusNoContent, nil)
 }
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kien-tn/chirpy/internal/database"
+	"github.com/kien-tn/chirpy/internal/realtime"
+)
+
+const (
+	maxConversationParticipants = 10
+	maxMessageLength            = 2000
+)
+
+type Conversation struct {
+	ID           uuid.UUID                 `json:"id"`
+	CreatedAt    time.Time                 `json:"created_at"`
+	UpdatedAt    time.Time                 `json:"updated_at"`
+	CreatedBy    uuid.UUID                 `json:"created_by"`
+	IsGroup      bool                      `json:"is_group"`
+	Participants []ConversationParticipant `json:"participants,omitempty"`
+	UnreadCount  int64                     `json:"unread_count"`
+}
+
+type ConversationParticipant struct {
+	UserID     uuid.UUID  `json:"user_id"`
+	JoinedAt   time.Time  `json:"joined_at"`
+	LastReadAt *time.Time `json:"last_read_at"`
+}
+
+type Message struct {
+	ID             uuid.UUID `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	SenderID       uuid.UUID `json:"sender_id"`
+	Body           string    `json:"body"`
+}
+
+func participantsFromRows(rows []database.ConversationParticipant) []ConversationParticipant {
+	out := []ConversationParticipant{}
+	for _, p := range rows {
+		cp := ConversationParticipant{
+			UserID:   p.UserID,
+			JoinedAt: p.JoinedAt,
+		}
+		if p.LastReadAt.Valid {
+			cp.LastReadAt = &p.LastReadAt.Time
+		}
+		out = append(out, cp)
+	}
+	return out
+}
+
+// conversationForParticipant loads the conversation in the request path,
+// answering 404 unless the authenticated user takes part in it.
+func (cfg *apiConfig) conversationForParticipant(w http.ResponseWriter, r *http.Request) (database.Conversation, bool) {
+	conversationID, err := uuid.Parse(r.PathValue("conversation_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid conversation ID format", err)
+		return database.Conversation{}, false
+	}
+	ok, err := cfg.db.IsConversationParticipant(r.Context(), database.IsConversationParticipantParams{
+		ConversationID: conversationID,
+		UserID:         userIDFromContext(r.Context()),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting conversation", err)
+		return database.Conversation{}, false
+	}
+	if !ok {
+		respondWithError(w, http.StatusNotFound, "Conversation not found", nil)
+		return database.Conversation{}, false
+	}
+	c, err := cfg.db.GetConversationByID(r.Context(), conversationID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting conversation", err)
+		return database.Conversation{}, false
+	}
+	return c, true
+}
+
+func (cfg *apiConfig) handlerCreateConversation(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		ParticipantIDs []uuid.UUID `json:"participant_ids"`
+	}
+	defer r.Body.Close()
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+	userID := userIDFromContext(r.Context())
+	members := []uuid.UUID{userID}
+	for _, id := range params.ParticipantIDs {
+		if id != uuid.Nil && !slices.Contains(members, id) {
+			members = append(members, id)
+		}
+	}
+	if len(members) < 2 {
+		respondWithError(w, http.StatusBadRequest, "At least one other participant is required", nil)
+		return
+	}
+	if len(members) > maxConversationParticipants {
+		respondWithError(w, http.StatusBadRequest, "Too many participants", nil)
+		return
+	}
+	// One-to-one conversations are keyed by the sorted pair of users so that
+	// starting a conversation twice returns the existing one.
+	directKey := sql.NullString{}
+	if len(members) == 2 {
+		pair := slices.Clone(members)
+		slices.SortFunc(pair, func(a, b uuid.UUID) int { return slices.Compare(a[:], b[:]) })
+		directKey = sql.NullString{String: pair[0].String() + ":" + pair[1].String(), Valid: true}
+	}
+
+	blocked, err := cfg.db.BlockExistsBetween(r.Context(), database.BlockExistsBetweenParams{
+		UserID:   userID,
+		OtherIds: members[1:],
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating conversation", err)
+		return
+	}
+	if blocked {
+		respondWithError(w, http.StatusForbidden, "You can't message this user", nil)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating conversation", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTracedTx(tx)
+	c, err := qtx.CreateConversation(r.Context(), database.CreateConversationParams{
+		CreatedBy: userID,
+		IsGroup:   len(members) > 2,
+		DirectKey: directKey,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating conversation", err)
+		return
+	}
+	for _, id := range members {
+		err = qtx.AddConversationParticipant(r.Context(), database.AddConversationParticipantParams{
+			ConversationID: c.ID,
+			UserID:         id,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Unknown participant", err)
+			return
+		}
+	}
+	participants, err := qtx.GetConversationParticipants(r.Context(), c.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating conversation", err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating conversation", err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, Conversation{
+		ID:           c.ID,
+		CreatedAt:    c.CreatedAt,
+		UpdatedAt:    c.UpdatedAt,
+		CreatedBy:    c.CreatedBy,
+		IsGroup:      c.IsGroup,
+		Participants: participantsFromRows(participants),
+	})
+}
+
+func (cfg *apiConfig) handlerGetConversations(w http.ResponseWriter, r *http.Request) {
+	cursor, limit, err := pageParams(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	rows, err := cfg.db.ListConversationsForUser(r.Context(), database.ListConversationsForUserParams{
+		UserID:     userIDFromContext(r.Context()),
+		CursorTime: cursor.Time,
+		CursorID:   cursor.ID,
+		PageSize:   limit,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting conversations", err)
+		return
+	}
+	output := []Conversation{}
+	for _, c := range rows {
+		output = append(output, Conversation{
+			ID:          c.ID,
+			CreatedAt:   c.CreatedAt,
+			UpdatedAt:   c.UpdatedAt,
+			CreatedBy:   c.CreatedBy,
+			IsGroup:     c.IsGroup,
+			UnreadCount: c.UnreadCount,
+		})
+	}
+	nextCursor := ""
+	if len(rows) == int(limit) {
+		last := rows[len(rows)-1]
+		nextCursor = encodeCursor(last.UpdatedAt, last.ID)
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"conversations": output,
+		"next_cursor":   nextCursor,
+	})
+}
+
+func (cfg *apiConfig) handlerGetConversation(w http.ResponseWriter, r *http.Request) {
+	c, ok := cfg.conversationForParticipant(w, r)
+	if !ok {
+		return
+	}
+	participants, err := cfg.db.GetConversationParticipants(r.Context(), c.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting conversation", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, Conversation{
+		ID:           c.ID,
+		CreatedAt:    c.CreatedAt,
+		UpdatedAt:    c.UpdatedAt,
+		CreatedBy:    c.CreatedBy,
+		IsGroup:      c.IsGroup,
+		Participants: participantsFromRows(participants),
+	})
+}
+
+func (cfg *apiConfig) handlerGetMessages(w http.ResponseWriter, r *http.Request) {
+	c, ok := cfg.conversationForParticipant(w, r)
+	if !ok {
+		return
+	}
+	cursor, limit, err := pageParams(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	rows, err := cfg.db.ListMessages(r.Context(), database.ListMessagesParams{
+		ConversationID: c.ID,
+		CursorTime:     cursor.Time,
+		CursorID:       cursor.ID,
+		PageSize:       limit,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting messages", err)
+		return
+	}
+	output := []Message{}
+	for _, m := range rows {
+		output = append(output, Message(m))
+	}
+	nextCursor := ""
+	if len(rows) == int(limit) {
+		last := rows[len(rows)-1]
+		nextCursor = encodeCursor(last.CreatedAt, last.ID)
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"messages":    output,
+		"next_cursor": nextCursor,
+	})
+}
+
+func (cfg *apiConfig) handlerSendMessage(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body string `json:"body"`
+	}
+	c, ok := cfg.conversationForParticipant(w, r)
+	if !ok {
+		return
+	}
+	defer r.Body.Close()
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+	if params.Body == "" {
+		respondWithError(w, http.StatusBadRequest, "Message body is required", nil)
+		return
+	}
+	if len(params.Body) > maxMessageLength {
+		respondWithError(w, http.StatusBadRequest, "Message is too long", nil)
+		return
+	}
+	participants, err := cfg.db.GetConversationParticipants(r.Context(), c.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting conversation", err)
+		return
+	}
+	userID := userIDFromContext(r.Context())
+	others := []uuid.UUID{}
+	for _, p := range participants {
+		if p.UserID != userID {
+			others = append(others, p.UserID)
+		}
+	}
+	blocked, err := cfg.db.BlockExistsBetween(r.Context(), database.BlockExistsBetweenParams{
+		UserID:   userID,
+		OtherIds: others,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error sending message", err)
+		return
+	}
+	if blocked {
+		respondWithError(w, http.StatusForbidden, "You can't message this user", nil)
+		return
+	}
+	m, err := cfg.db.CreateMessage(r.Context(), database.CreateMessageParams{
+		ConversationID: c.ID,
+		SenderID:       userID,
+		Body:           params.Body,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error sending message", err)
+		return
+	}
+	if err := cfg.db.TouchConversation(r.Context(), c.ID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error sending message", err)
+		return
+	}
+	for _, p := range participants {
+		cfg.hub.PublishToUser(p.UserID, realtime.TopicMessages, "message.created", Message(m))
+	}
+	respondWithJSON(w, http.StatusCreated, Message(m))
+}
+
+func (cfg *apiConfig) handlerMarkConversationRead(w http.ResponseWriter, r *http.Request) {
+	c, ok := cfg.conversationForParticipant(w, r)
+	if !ok {
+		return
+	}
+	p, err := cfg.db.MarkConversationRead(r.Context(), database.MarkConversationReadParams{
+		ConversationID: c.ID,
+		UserID:         userIDFromContext(r.Context()),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Conversation not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating conversation", err)
+		return
+	}
+	participants, err := cfg.db.GetConversationParticipants(r.Context(), c.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting conversation", err)
+		return
+	}
+	receipt := participantsFromRows([]database.ConversationParticipant{p})[0]
+	for _, other := range participants {
+		if other.UserID != p.UserID {
+			cfg.hub.PublishToUser(other.UserID, realtime.TopicMessages, "conversation.read", map[string]interface{}{
+				"conversation_id": c.ID,
+				"user_id":         receipt.UserID,
+				"last_read_at":    receipt.LastReadAt,
+			})
+		}
+	}
+	respondWithJSON(w, http.StatusOK, receipt)
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kien-tn/chirpy/internal/database"
+)
+
+// Draft is an unpublished chirp. Drafts with a publish_at time are scheduled
+// and published automatically.
+type Draft struct {
+	ID        uuid.UUID   `json:"id"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+	Body      string      `json:"body"`
+	MediaIDs  []uuid.UUID `json:"media_ids"`
+	PublishAt *time.Time  `json:"publish_at"`
+	Poll      *pollParams `json:"poll"`
+	// QuotedChirpID makes the draft a quote of another chirp.
+	QuotedChirpID  *uuid.UUID `json:"quoted_chirp_id"`
+	ContentWarning string     `json:"content_warning"`
+	SensitiveMedia bool       `json:"sensitive_media"`
+}
+
+// draftParams is the content of a draft as sent by the client.
+type draftParams struct {
+	Body      string      `json:"body"`
+	MediaIDs  []uuid.UUID `json:"media_ids"`
+	PublishAt *time.Time  `json:"publish_at"`
+	Poll      *pollParams `json:"poll"`
+	// QuotedChirpID makes the draft a quote of another chirp.
+	QuotedChirpID  *uuid.UUID `json:"quoted_chirp_id"`
+	ContentWarning string     `json:"content_warning"`
+	SensitiveMedia bool       `json:"sensitive_media"`
+}
+
+func draftResponse(d database.ChirpDraft) Draft {
+	draft := Draft{
+		ID:             d.ID,
+		CreatedAt:      d.CreatedAt,
+		UpdatedAt:      d.UpdatedAt,
+		Body:           d.Body,
+		MediaIDs:       d.MediaIds,
+		ContentWarning: d.ContentWarning,
+		SensitiveMedia: d.SensitiveMedia,
+	}
+	if draft.MediaIDs == nil {
+		draft.MediaIDs = []uuid.UUID{}
+	}
+	if d.PublishAt.Valid {
+		draft.PublishAt = &d.PublishAt.Time
+	}
+	if d.QuotedChirpID.Valid {
+		draft.QuotedChirpID = &d.QuotedChirpID.UUID
+	}
+	if len(d.PollOptions) > 0 {
+		draft.Poll = &pollParams{Options: d.PollOptions, ClosesAt: d.PollClosesAt.Time}
+	}
+	return draft
+}
+
+func draftResponses(drafts []database.ChirpDraft) []Draft {
+	output := make([]Draft, 0, len(drafts))
+	for _, d := range drafts {
+		output = append(output, draftResponse(d))
+	}
+	return output
+}
+
+// validateDraft applies the checks a chirp gets when it is posted, so a
+// scheduled chirp doesn't fail later. It returns a message for the client
+// when the draft is invalid. The content warning is trimmed in place.
+func (cfg *apiConfig) validateDraft(ctx context.Context, userID uuid.UUID, p *draftParams) (string, error) {
+	if len(p.Body) > maxChirpLength {
+		return "Chirp is too long", nil
+	}
+	p.ContentWarning = strings.TrimSpace(p.ContentWarning)
+	if len([]rune(p.ContentWarning)) > maxContentWarningLength {
+		return "Content warning must be at most 100 characters", nil
+	}
+	if len(p.MediaIDs) > maxChirpMedia {
+		return "A chirp can have at most 4 media attachments", nil
+	}
+	if p.PublishAt != nil && !p.PublishAt.After(time.Now()) {
+		return "publish_at must be in the future", nil
+	}
+	if p.Poll != nil {
+		// A scheduled poll opens when its chirp is published.
+		opensAt := time.Now()
+		if p.PublishAt != nil {
+			opensAt = *p.PublishAt
+		}
+		if msg := validatePoll(*p.Poll, opensAt); msg != "" {
+			return msg, nil
+		}
+	}
+	if p.QuotedChirpID != nil {
+		ok, err := cfg.canQuote(ctx, userID, *p.QuotedChirpID)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return "Invalid quoted chirp ID", nil
+		}
+	}
+	if len(p.MediaIDs) == 0 {
+		return "", nil
+	}
+	n, err := cfg.db.CountAttachableMedia(ctx, database.CountAttachableMediaParams{
+		Ids:    p.MediaIDs,
+		UserID: userID,
+	})
+	if err != nil {
+		return "", err
+	}
+	if n != int64(len(p.MediaIDs)) {
+		return "Invalid media ID", nil
+	}
+	return "", nil
+}
+
+// publishAtParam converts an optional publish time to UTC, which is how the
+// scheduler compares it.
+func publishAtParam(publishAt *time.Time) sql.NullTime {
+	if publishAt == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: publishAt.UTC(), Valid: true}
+}
+
+// quotedChirpParam converts an optional quoted chirp ID to a column value.
+func quotedChirpParam(id *uuid.UUID) uuid.NullUUID {
+	if id == nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: *id, Valid: true}
+}
+
+// pollColumns splits an optional poll into the draft's poll columns.
+func pollColumns(p *pollParams) ([]string, sql.NullTime) {
+	if p == nil {
+		return []string{}, sql.NullTime{}
+	}
+	return p.Options, sql.NullTime{Time: p.ClosesAt.UTC(), Valid: true}
+}
+
+func (cfg *apiConfig) createDraft(w http.ResponseWriter, r *http.Request, userID uuid.UUID, p draftParams) {
+	msg, err := cfg.validateDraft(r.Context(), userID, &p)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error saving draft", err)
+		return
+	}
+	if msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg, nil)
+		return
+	}
+	if p.PublishAt != nil && !cfg.allowChirp(w, r, userID) {
+		return
+	}
+	if p.MediaIDs == nil {
+		p.MediaIDs = []uuid.UUID{}
+	}
+	pollOptions, pollClosesAt := pollColumns(p.Poll)
+	d, err := cfg.db.CreateDraft(r.Context(), database.CreateDraftParams{
+		UserID:         userID,
+		Body:           p.Body,
+		MediaIds:       p.MediaIDs,
+		PublishAt:      publishAtParam(p.PublishAt),
+		PollOptions:    pollOptions,
+		PollClosesAt:   pollClosesAt,
+		QuotedChirpID:  quotedChirpParam(p.QuotedChirpID),
+		ContentWarning: p.ContentWarning,
+		SensitiveMedia: p.SensitiveMedia,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error saving draft", err)
+		return
+	}
+	respondWithJSON(w, http.StatusAccepted, draftResponse(d))
+}
+
+func (cfg *apiConfig) handlerCreateDraft(w http.ResponseWriter, r *http.Request) {
+	params := draftParams{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	cfg.createDraft(w, r, userIDFromContext(r.Context()), params)
+}
+
+func (cfg *apiConfig) handlerGetDrafts(w http.ResponseWriter, r *http.Request) {
+	drafts, err := cfg.db.GetDraftsByUserID(r.Context(), userIDFromContext(r.Context()))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting drafts", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, draftResponses(drafts))
+}
+
+func (cfg *apiConfig) handlerGetScheduled(w http.ResponseWriter, r *http.Request) {
+	drafts, err := cfg.db.GetScheduledByUserID(r.Context(), userIDFromContext(r.Context()))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting scheduled chirps", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, draftResponses(drafts))
+}
+
+// handlerUpdateDraft replaces a draft's contents. Setting publish_at schedules
+// the draft and clearing it turns a scheduled chirp back into a draft. A draft
+// that is being published concurrently is gone by the time the update runs,
+// so it reports 404.
+func (cfg *apiConfig) handlerUpdateDraft(w http.ResponseWriter, r *http.Request) {
+	draftID, err := uuid.Parse(r.PathValue("draft_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid draft ID format", err)
+		return
+	}
+	params := draftParams{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	userID := userIDFromContext(r.Context())
+	msg, err := cfg.validateDraft(r.Context(), userID, &params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error saving draft", err)
+		return
+	}
+	if msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg, nil)
+		return
+	}
+	if params.PublishAt != nil && !cfg.allowChirp(w, r, userID) {
+		return
+	}
+	if params.MediaIDs == nil {
+		params.MediaIDs = []uuid.UUID{}
+	}
+	pollOptions, pollClosesAt := pollColumns(params.Poll)
+	d, err := cfg.db.UpdateDraft(r.Context(), database.UpdateDraftParams{
+		ID:             draftID,
+		UserID:         userID,
+		Body:           params.Body,
+		MediaIds:       params.MediaIDs,
+		PublishAt:      publishAtParam(params.PublishAt),
+		PollOptions:    pollOptions,
+		PollClosesAt:   pollClosesAt,
+		QuotedChirpID:  quotedChirpParam(params.QuotedChirpID),
+		ContentWarning: params.ContentWarning,
+		SensitiveMedia: params.SensitiveMedia,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Draft not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error saving draft", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, draftResponse(d))
+}
+
+func (cfg *apiConfig) handlerDeleteDraft(w http.ResponseWriter, r *http.Request) {
+	draftID, err := uuid.Parse(r.PathValue("draft_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid draft ID format", err)
+		return
+	}
+	n, err := cfg.db.DeleteDraft(r.Context(), database.DeleteDraftParams{
+		ID:     draftID,
+		UserID: userIDFromContext(r.Context()),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error deleting draft", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, "Draft not found", nil)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
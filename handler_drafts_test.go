@@ -0,0 +1,57 @@
+package main
+
+import (
+	"database/sql"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kien-tn/chirpy/internal/database"
+)
+
+func TestDraftColumnsRoundTrip(t *testing.T) {
+	publishAt := time.Now().Add(time.Hour)
+	quotedID := uuid.New()
+	poll := &pollParams{Options: []string{"Yes", "No"}, ClosesAt: publishAt.Add(time.Hour)}
+	pollOptions, pollClosesAt := pollColumns(poll)
+	d := database.ChirpDraft{
+		Body:           "hello",
+		PublishAt:      publishAtParam(&publishAt),
+		PollOptions:    pollOptions,
+		PollClosesAt:   pollClosesAt,
+		QuotedChirpID:  quotedChirpParam(&quotedID),
+		ContentWarning: "spoilers",
+	}
+
+	got := draftResponse(d)
+	if got.MediaIDs == nil {
+		t.Errorf("MediaIDs = nil, want an empty list")
+	}
+	if got.PublishAt == nil || !got.PublishAt.Equal(publishAt) {
+		t.Errorf("PublishAt = %v, want %v", got.PublishAt, publishAt)
+	}
+	if got.Poll == nil || !slices.Equal(got.Poll.Options, poll.Options) || !got.Poll.ClosesAt.Equal(poll.ClosesAt) {
+		t.Errorf("Poll = %+v, want %+v", got.Poll, poll)
+	}
+	if got.QuotedChirpID == nil || *got.QuotedChirpID != quotedID {
+		t.Errorf("QuotedChirpID = %v, want %v", got.QuotedChirpID, quotedID)
+	}
+	if got.ContentWarning != "spoilers" {
+		t.Errorf("ContentWarning = %q, want spoilers", got.ContentWarning)
+	}
+}
+
+func TestDraftColumnsEmpty(t *testing.T) {
+	pollOptions, pollClosesAt := pollColumns(nil)
+	if pollOptions == nil || len(pollOptions) != 0 || pollClosesAt.Valid {
+		t.Errorf("pollColumns(nil) = %v, %v; want no poll", pollOptions, pollClosesAt)
+	}
+	if publishAtParam(nil) != (sql.NullTime{}) || quotedChirpParam(nil).Valid {
+		t.Errorf("nil parameters produced non-NULL columns")
+	}
+	got := draftResponse(database.ChirpDraft{})
+	if got.PublishAt != nil || got.Poll != nil || got.QuotedChirpID != nil {
+		t.Errorf("draftResponse of an empty draft = %+v", got)
+	}
+}
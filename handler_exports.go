@@ -0,0 +1,354 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kien-tn/chirpy/internal/auth"
+	"github.com/kien-tn/chirpy/internal/database"
+)
+
+const (
+	// exportRetention is how long a finished archive is kept.
+	exportRetention = 7 * 24 * time.Hour
+	// exportLinkLifetime bounds each signed download link. A fresh link is
+	// handed out every time the export is fetched.
+	exportLinkLifetime = 15 * time.Minute
+	exportBatchSize    = 5
+)
+
+type DataExport struct {
+	ID          uuid.UUID  `json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Status      string     `json:"status"`
+	SizeBytes   int64      `json:"size_bytes"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	DownloadURL string     `json:"download_url,omitempty"`
+}
+
+func exportDownloadPath(id uuid.UUID) string {
+	return "/api/exports/" + id.String() + "/download"
+}
+
+func (cfg *apiConfig) exportResponse(e database.DataExport) DataExport {
+	out := DataExport{
+		ID:        e.ID,
+		CreatedAt: e.CreatedAt,
+		Status:    e.Status,
+		SizeBytes: e.SizeBytes,
+	}
+	if e.ExpiresAt.Valid {
+		out.ExpiresAt = &e.ExpiresAt.Time
+	}
+	if e.Status == "ready" {
+		expires := time.Now().Add(exportLinkLifetime)
+		if e.ExpiresAt.Valid && e.ExpiresAt.Time.Before(expires) {
+			expires = e.ExpiresAt.Time
+		}
+		path := exportDownloadPath(e.ID)
+		out.DownloadURL = fmt.Sprintf("%s?expires=%d&signature=%s",
+			path, expires.Unix(), auth.SignURL(path, expires, cfg.secretKey))
+	}
+	return out
+}
+
+func (cfg *apiConfig) handlerCreateExport(w http.ResponseWriter, r *http.Request) {
+	e, err := cfg.db.CreateDataExport(r.Context(), userIDFromContext(r.Context()))
+	if isUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "An export is already in progress", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating export", err)
+		return
+	}
+	respondWithJSON(w, http.StatusAccepted, cfg.exportResponse(e))
+}
+
+func (cfg *apiConfig) handlerGetExport(w http.ResponseWriter, r *http.Request) {
+	exportID, err := uuid.Parse(r.PathValue("export_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid export ID format", err)
+		return
+	}
+	e, err := cfg.db.GetDataExport(r.Context(), database.GetDataExportParams{
+		ID:     exportID,
+		UserID: userIDFromContext(r.Context()),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Export not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting export", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, cfg.exportResponse(e))
+}
+
+// handlerDownloadExport serves an archive to anyone holding a valid signed
+// link, so it can be opened directly in a browser.
+func (cfg *apiConfig) handlerDownloadExport(w http.ResponseWriter, r *http.Request) {
+	exportID, err := uuid.Parse(r.PathValue("export_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid export ID format", err)
+		return
+	}
+	unix, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusForbidden, "Invalid download link", err)
+		return
+	}
+	err = auth.ValidateSignedURL(exportDownloadPath(exportID), time.Unix(unix, 0), r.URL.Query().Get("signature"), cfg.secretKey)
+	if err != nil {
+		respondWithError(w, http.StatusForbidden, "Invalid download link", err)
+		return
+	}
+	e, err := cfg.db.GetDataExportByID(r.Context(), exportID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && e.Status != "ready") {
+		respondWithError(w, http.StatusNotFound, "Export not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting export", err)
+		return
+	}
+	rc, err := cfg.exports.Get(r.Context(), e.StorageKey)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Export not found", err)
+		return
+	}
+	defer rc.Close()
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="chirpy-export.zip"`)
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Header().Set("Content-Length", strconv.FormatInt(e.SizeBytes, 10))
+	extendWriteDeadline(w)
+	io.Copy(w, rc)
+}
+
+// processDataExports builds pending archives. Exports are claimed with SKIP
+// LOCKED, and a claim left behind by a crashed instance is retried after an
+// hour.
+func (cfg *apiConfig) processDataExports(ctx context.Context) (int, error) {
+	processed := 0
+	for processed < exportBatchSize {
+		e, err := cfg.db.ClaimDataExport(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			return processed, nil
+		}
+		if err != nil {
+			return processed, err
+		}
+		processed++
+		key := e.ID.String() + ".zip"
+		size, err := cfg.buildExport(ctx, e.UserID, key)
+		if err != nil {
+			slog.Error("Error building export", "export_id", e.ID, "error", err)
+			if err := cfg.db.FailDataExport(ctx, e.ID); err != nil {
+				return processed, err
+			}
+			continue
+		}
+		err = cfg.db.CompleteDataExport(ctx, database.CompleteDataExportParams{
+			ID:         e.ID,
+			StorageKey: key,
+			SizeBytes:  size,
+			ExpiresAt:  sql.NullTime{Time: time.Now().Add(exportRetention).UTC(), Valid: true},
+		})
+		if err != nil {
+			return processed, err
+		}
+	}
+	return processed, nil
+}
+
+type exportedProfile struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	Email       string    `json:"email"`
+	Handle      string    `json:"handle"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	Location    string    `json:"location"`
+	AvatarURL   string    `json:"avatar_url"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
+}
+
+type exportedChirp struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	Body      string     `json:"body"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	Media     []string   `json:"media"`
+}
+
+type exportedSession struct {
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+type exportedRelationships struct {
+	Blocked []Relationship `json:"blocked"`
+	Muted   []Relationship `json:"muted"`
+}
+
+// buildExport writes a zip of the user's profile, chirps, sessions and
+// relationships as JSON, plus the original files they uploaded under media/.
+// The archive is staged in a temporary file since media can be large.
+func (cfg *apiConfig) buildExport(ctx context.Context, userID uuid.UUID, key string) (int64, error) {
+	u, err := cfg.db.GetUserByID(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	chirps, err := cfg.db.GetChirpsForExport(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	media, err := cfg.db.GetMediaByUserID(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	sessions, err := cfg.db.GetSessionsByUserID(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	blocks, err := cfg.db.GetBlocksByBlocker(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	mutes, err := cfg.db.GetMutesByMuter(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	tmp, err := os.CreateTemp("", "chirpy-export-*.zip")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	zw := zip.NewWriter(tmp)
+
+	profile := exportedProfile{
+		ID:          u.ID,
+		CreatedAt:   u.CreatedAt,
+		Email:       u.Email,
+		Handle:      u.Handle.String,
+		DisplayName: u.DisplayName,
+		Bio:         u.Bio,
+		Location:    u.Location,
+		AvatarURL:   u.AvatarUrl,
+		IsChirpyRed: u.IsChirpyRed,
+	}
+	chirpMedia := map[uuid.UUID][]string{}
+	for _, m := range media {
+		if m.ChirpID.Valid {
+			chirpMedia[m.ChirpID.UUID] = append(chirpMedia[m.ChirpID.UUID], "media/"+m.StorageKey)
+		}
+	}
+	exportedChirps := make([]exportedChirp, 0, len(chirps))
+	for _, c := range chirps {
+		ec := exportedChirp{ID: c.ID, CreatedAt: c.CreatedAt, Body: c.Body, Media: chirpMedia[c.ID]}
+		if ec.Media == nil {
+			ec.Media = []string{}
+		}
+		if c.DeletedAt.Valid {
+			ec.DeletedAt = &c.DeletedAt.Time
+		}
+		exportedChirps = append(exportedChirps, ec)
+	}
+	exportedSessions := make([]exportedSession, 0, len(sessions))
+	for _, s := range sessions {
+		es := exportedSession{CreatedAt: s.CreatedAt, ExpiresAt: s.ExpiredAt}
+		if s.RevokedAt.Valid {
+			es.RevokedAt = &s.RevokedAt.Time
+		}
+		exportedSessions = append(exportedSessions, es)
+	}
+	relationships := exportedRelationships{Blocked: []Relationship{}, Muted: []Relationship{}}
+	for _, b := range blocks {
+		relationships.Blocked = append(relationships.Blocked, Relationship{UserID: b.BlockedID, CreatedAt: b.CreatedAt})
+	}
+	for _, m := range mutes {
+		relationships.Muted = append(relationships.Muted, Relationship{UserID: m.MutedID, CreatedAt: m.CreatedAt})
+	}
+
+	for name, v := range map[string]interface{}{
+		"profile.json":       profile,
+		"chirps.json":        exportedChirps,
+		"sessions.json":      exportedSessions,
+		"relationships.json": relationships,
+	} {
+		f, err := zw.Create(name)
+		if err != nil {
+			return 0, err
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(v); err != nil {
+			return 0, err
+		}
+	}
+	for _, m := range media {
+		if err := cfg.copyBlobToZip(ctx, zw, "media/"+m.StorageKey, m.StorageKey); err != nil {
+			return 0, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return 0, err
+	}
+
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	if err := cfg.exports.Put(ctx, key, tmp, "application/zip"); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+func (cfg *apiConfig) copyBlobToZip(ctx context.Context, zw *zip.Writer, name, key string) error {
+	rc, err := cfg.blobs.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	// Images are already compressed.
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, rc)
+	return err
+}
+
+// expireDataExports deletes archives whose retention has passed.
+func (cfg *apiConfig) expireDataExports(ctx context.Context) (int, error) {
+	keys, err := cfg.db.DeleteExpiredDataExports(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, key := range keys {
+		if err := cfg.exports.Delete(ctx, key); err != nil {
+			slog.Error("Error deleting export", "key", key, "error", err)
+		}
+	}
+	return len(keys), nil
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kien-tn/chirpy/internal/database"
+)
+
+const (
+	maxListNameLength        = 25
+	maxListDescriptionLength = 100
+	maxListMembers           = 500
+)
+
+type List struct {
+	ID          uuid.UUID    `json:"id"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	MemberCount int64        `json:"member_count"`
+	Members     []ListMember `json:"members,omitempty"`
+}
+
+type ListMember struct {
+	UserID      uuid.UUID `json:"user_id"`
+	Handle      string    `json:"handle"`
+	DisplayName string    `json:"display_name"`
+	AddedAt     time.Time `json:"added_at"`
+}
+
+// listForOwner loads the {list_id} list, writing a 404 unless it belongs to
+// the caller. Lists are private, so other users' lists don't exist as far as
+// the caller can tell.
+func (cfg *apiConfig) listForOwner(w http.ResponseWriter, r *http.Request) (database.List, bool) {
+	listID, err := uuid.Parse(r.PathValue("list_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid list ID format", err)
+		return database.List{}, false
+	}
+	l, err := cfg.db.GetListByID(r.Context(), database.GetListByIDParams{
+		ID:     listID,
+		UserID: userIDFromContext(r.Context()),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "List not found", err)
+		return database.List{}, false
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting list", err)
+		return database.List{}, false
+	}
+	return l, true
+}
+
+func (cfg *apiConfig) handlerCreateList(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	params.Name = strings.TrimSpace(params.Name)
+	if params.Name == "" || len([]rune(params.Name)) > maxListNameLength {
+		respondWithError(w, http.StatusBadRequest, "List name must be 1-25 characters", nil)
+		return
+	}
+	if len([]rune(params.Description)) > maxListDescriptionLength {
+		respondWithError(w, http.StatusBadRequest, "List description is too long", nil)
+		return
+	}
+	l, err := cfg.db.CreateList(r.Context(), database.CreateListParams{
+		UserID:      userIDFromContext(r.Context()),
+		Name:        params.Name,
+		Description: params.Description,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating list", err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, List{
+		ID:          l.ID,
+		CreatedAt:   l.CreatedAt,
+		UpdatedAt:   l.UpdatedAt,
+		Name:        l.Name,
+		Description: l.Description,
+	})
+}
+
+func (cfg *apiConfig) handlerGetLists(w http.ResponseWriter, r *http.Request) {
+	rows, err := cfg.db.GetListsByUserID(r.Context(), userIDFromContext(r.Context()))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting lists", err)
+		return
+	}
+	output := []List{}
+	for _, l := range rows {
+		output = append(output, List{
+			ID:          l.ID,
+			CreatedAt:   l.CreatedAt,
+			UpdatedAt:   l.UpdatedAt,
+			Name:        l.Name,
+			Description: l.Description,
+			MemberCount: l.MemberCount,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, output)
+}
+
+func (cfg *apiConfig) handlerGetList(w http.ResponseWriter, r *http.Request) {
+	l, ok := cfg.listForOwner(w, r)
+	if !ok {
+		return
+	}
+	rows, err := cfg.db.GetListMembers(r.Context(), l.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting list", err)
+		return
+	}
+	members := []ListMember{}
+	for _, m := range rows {
+		members = append(members, ListMember{
+			UserID:      m.ID,
+			Handle:      m.Handle.String,
+			DisplayName: m.DisplayName,
+			AddedAt:     m.CreatedAt,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, List{
+		ID:          l.ID,
+		CreatedAt:   l.CreatedAt,
+		UpdatedAt:   l.UpdatedAt,
+		Name:        l.Name,
+		Description: l.Description,
+		MemberCount: int64(len(members)),
+		Members:     members,
+	})
+}
+
+func (cfg *apiConfig) handlerDeleteList(w http.ResponseWriter, r *http.Request) {
+	listID, err := uuid.Parse(r.PathValue("list_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid list ID format", err)
+		return
+	}
+	n, err := cfg.db.DeleteList(r.Context(), database.DeleteListParams{
+		ID:     listID,
+		UserID: userIDFromContext(r.Context()),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error deleting list", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, "List not found", nil)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerAddListMember(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		UserID uuid.UUID `json:"user_id"`
+	}
+	l, ok := cfg.listForOwner(w, r)
+	if !ok {
+		return
+	}
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	u, err := cfg.db.GetUserByID(r.Context(), params.UserID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && u.DeletedAt.Valid) {
+		respondWithError(w, http.StatusNotFound, "User not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error adding list member", err)
+		return
+	}
+	count, err := cfg.db.CountListMembers(r.Context(), l.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error adding list member", err)
+		return
+	}
+	if count >= maxListMembers {
+		respondWithError(w, http.StatusBadRequest, "A list can have at most 500 members", nil)
+		return
+	}
+	err = cfg.db.AddListMember(r.Context(), database.AddListMemberParams{
+		ListID: l.ID,
+		UserID: u.ID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error adding list member", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerRemoveListMember(w http.ResponseWriter, r *http.Request) {
+	l, ok := cfg.listForOwner(w, r)
+	if !ok {
+		return
+	}
+	memberID, err := uuid.Parse(r.PathValue("user_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID format", err)
+		return
+	}
+	n, err := cfg.db.RemoveListMember(r.Context(), database.RemoveListMemberParams{
+		ListID: l.ID,
+		UserID: memberID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error removing list member", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, "User is not on this list", nil)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerGetListChirps is the list's timeline: its members' chirps, newest
+// first, with the same block and mute rules as GET /api/chirps.
+func (cfg *apiConfig) handlerGetListChirps(w http.ResponseWriter, r *http.Request) {
+	l, ok := cfg.listForOwner(w, r)
+	if !ok {
+		return
+	}
+	cursor, limit, err := pageParams(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	chirps, err := cfg.db.ListListChirps(r.Context(), database.ListListChirpsParams{
+		ListID:     l.ID,
+		ViewerID:   l.UserID,
+		CursorTime: cursor.Time,
+		CursorID:   cursor.ID,
+		PageSize:   limit,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting chirps", err)
+		return
+	}
+	output, err := cfg.chirpResponses(r.Context(), chirps, l.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting chirps", err)
+		return
+	}
+	nextCursor := ""
+	if len(chirps) == int(limit) {
+		last := chirps[len(chirps)-1]
+		nextCursor = encodeCursor(last.CreatedAt, last.ID)
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"chirps":      output,
+		"next_cursor": nextCursor,
+	})
+}
@@ -1,8 +1,9 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
-	"log"
+	"errors"
 	"net/http"
 	"time"
 
@@ -21,7 +22,7 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 	params := parameters{}
 	err := decoder.Decode(&params)
 	if err != nil {
-		log.Printf("Error decoding parameters: %s", err)
+		loggerFromContext(r.Context()).Info("Error decoding parameters", "error", err)
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte(`{"error": "Invalid request payload"}`))
 		return
@@ -36,15 +37,32 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 	}
 	// get the user with apiCfg.db.GetUserByEmail
 	u, err := cfg.db.GetUserByEmail(r.Context(), params.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		cfg.audit(r, auditEntry{Action: auditLogin, Failed: true, Details: "Unknown " + cfg.auditEmail(params.Email)})
+		cfg.metrics.login(true)
+		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password", err)
+		return
+	}
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error getting user", err)
 		return
 	}
+	loginFailed := auditEntry{Action: auditLogin, Failed: true, ActorID: u.ID, TargetType: auditTargetUser, TargetID: u.ID}
 	// check if the password is correct
 	if err := auth.CheckPasswordHash(u.HashedPassword, params.Password); err != nil {
+		loginFailed.Details = "Incorrect password"
+		cfg.audit(r, loginFailed)
+		cfg.metrics.login(true)
 		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password", err)
 		return
 	}
+	if accountState(u) == accountSuspended {
+		loginFailed.Details = "Account suspended"
+		cfg.audit(r, loginFailed)
+		cfg.metrics.login(true)
+		respondWithError(w, http.StatusForbidden, suspendedMessage(u), nil)
+		return
+	}
 	token, err := auth.MakeJWT(u.ID, cfg.secretKey, time.Duration(params.ExpiresInSeconds)*time.Second)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error creating token", err)
@@ -64,12 +82,15 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusInternalServerError, "Error creating refresh token", err)
 		return
 	}
+	cfg.audit(r, auditEntry{Action: auditLogin, ActorID: u.ID, TargetType: auditTargetUser, TargetID: u.ID})
+	cfg.metrics.login(false)
 
 	respondWithJSON(w, http.StatusOK, User{
 		ID:           u.ID,
 		CreatedAt:    u.CreatedAt,
 		UpdatedAt:    u.UpdatedAt,
 		Email:        u.Email,
+		Handle:       u.Handle.String,
 		Token:        token,
 		RefreshToken: refreshToken,
 		IsChirpyRed:  u.IsChirpyRed,
@@ -84,22 +105,40 @@ func (cfg *apiConfig) handlerRefreshToken(w http.ResponseWriter, r *http.Request
 	}
 	rt, err := cfg.db.GetUserFromRefreshToken(r.Context(), refreshToken)
 	if err != nil {
+		cfg.audit(r, auditEntry{Action: auditTokenRefresh, Failed: true, Details: "Unknown refresh token"})
 		respondWithError(w, http.StatusUnauthorized, "Invalid refresh token", err)
 		return
 	}
+	refreshFailed := auditEntry{Action: auditTokenRefresh, Failed: true, ActorID: rt.UserID, TargetType: auditTargetUser, TargetID: rt.UserID}
 	if rt.RevokedAt.Valid {
+		refreshFailed.Details = "Refresh token revoked"
+		cfg.audit(r, refreshFailed)
 		respondWithError(w, http.StatusUnauthorized, "Refresh token revoked", nil)
 		return
 	}
 	if rt.ExpiredAt.Before(time.Now()) {
+		refreshFailed.Details = "Refresh token expired"
+		cfg.audit(r, refreshFailed)
 		respondWithError(w, http.StatusUnauthorized, "Refresh token expired", nil)
 		return
 	}
+	u, err := cfg.db.GetUserByID(r.Context(), rt.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting user", err)
+		return
+	}
+	if accountState(u) == accountSuspended {
+		refreshFailed.Details = "Account suspended"
+		cfg.audit(r, refreshFailed)
+		respondWithError(w, http.StatusForbidden, suspendedMessage(u), nil)
+		return
+	}
 	token, err := auth.MakeJWT(rt.UserID, cfg.secretKey, 3600*time.Second)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error creating token", err)
 		return
 	}
+	cfg.audit(r, auditEntry{Action: auditTokenRefresh, ActorID: rt.UserID, TargetType: auditTargetUser, TargetID: rt.UserID})
 	respondWithJSON(w, http.StatusOK, map[string]interface{}{
 		"token": token,
 	})
@@ -111,11 +150,12 @@ func (cfg *apiConfig) handlerRevokeRefreshToken(w http.ResponseWriter, r *http.R
 		respondWithError(w, http.StatusUnauthorized, "Authorization header required", err)
 		return
 	}
-	_, err = cfg.db.RevokeRefreshToken(r.Context(), refreshToken)
+	rt, err := cfg.db.RevokeRefreshToken(r.Context(), refreshToken)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error revoking refresh token", err)
 		return
 	}
+	cfg.audit(r, auditEntry{Action: auditTokenRevoke, ActorID: rt.UserID, TargetType: auditTargetUser, TargetID: rt.UserID})
 	// respond with a 204 No Content
 	w.WriteHeader(http.StatusNoContent)
 
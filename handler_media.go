@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+
+	"github.com/google/uuid"
+	"github.com/kien-tn/chirpy/internal/blob"
+	"github.com/kien-tn/chirpy/internal/database"
+	"github.com/kien-tn/chirpy/internal/media"
+)
+
+const (
+	maxUploadSize = 5 << 20
+	maxChirpMedia = 4
+)
+
+type ChirpMedia struct {
+	ID           uuid.UUID `json:"id"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	ContentType  string    `json:"content_type"`
+	Width        int32     `json:"width"`
+	Height       int32     `json:"height"`
+}
+
+func (cfg *apiConfig) mediaResponse(m database.MediaAttachment) ChirpMedia {
+	return ChirpMedia{
+		ID:           m.ID,
+		URL:          cfg.blobs.URL(m.StorageKey),
+		ThumbnailURL: cfg.blobs.URL(m.ThumbnailKey),
+		ContentType:  m.ContentType,
+		Width:        m.Width,
+		Height:       m.Height,
+	}
+}
+
+func (cfg *apiConfig) handlerUploadMedia(w http.ResponseWriter, r *http.Request) {
+	// Leave some room for the multipart framing around the file itself.
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize+1<<20)
+	file, _, err := r.FormFile("file")
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "File is too large", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "A multipart \"file\" field is required", err)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxUploadSize+1))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error reading file", err)
+		return
+	}
+	if len(data) > maxUploadSize {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "File is too large", nil)
+		return
+	}
+	img, err := media.Process(data)
+	if errors.Is(err, media.ErrUnsupportedType) {
+		respondWithError(w, http.StatusUnsupportedMediaType, "Only JPEG, PNG and GIF images are supported", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid image", err)
+		return
+	}
+
+	id := uuid.New()
+	key := id.String() + media.Extension(img.ContentType)
+	thumbKey := id.String() + "_thumb" + media.Extension(img.ThumbnailContentType)
+	if err := cfg.blobs.Put(r.Context(), key, bytes.NewReader(img.Data), img.ContentType); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error storing file", err)
+		return
+	}
+	if err := cfg.blobs.Put(r.Context(), thumbKey, bytes.NewReader(img.Thumbnail), img.ThumbnailContentType); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error storing file", err)
+		return
+	}
+	m, err := cfg.db.CreateMediaAttachment(r.Context(), database.CreateMediaAttachmentParams{
+		ID:           id,
+		UserID:       userIDFromContext(r.Context()),
+		ContentType:  img.ContentType,
+		StorageKey:   key,
+		ThumbnailKey: thumbKey,
+		Width:        int32(img.Width),
+		Height:       int32(img.Height),
+		SizeBytes:    int64(len(img.Data)),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error saving media", err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, cfg.mediaResponse(m))
+}
+
+// handlerServeMedia serves blobs when they live on local disk. Keys are unique
+// per upload, so responses can be cached forever.
+func (cfg *apiConfig) handlerServeMedia(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	rc, err := cfg.blobs.Get(r.Context(), key)
+	if errors.Is(err, blob.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid media key", err)
+		return
+	}
+	defer rc.Close()
+	w.Header().Set("Content-Type", mime.TypeByExtension(path.Ext(key)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	io.Copy(w, rc)
+}
+
+// chirpMedia loads the attachments of each chirp, in the order they were given.
+func (cfg *apiConfig) chirpMedia(ctx context.Context, chirpIDs []uuid.UUID) (map[uuid.UUID][]ChirpMedia, error) {
+	attachments, err := cfg.db.GetMediaByChirpIDs(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	byChirp := map[uuid.UUID][]ChirpMedia{}
+	for _, m := range attachments {
+		byChirp[m.ChirpID.UUID] = append(byChirp[m.ChirpID.UUID], cfg.mediaResponse(m))
+	}
+	return byChirp, nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/kien-tn/chirpy/internal/database"
+	"github.com/kien-tn/chirpy/internal/realtime"
+)
+
+const (
+	roleModerator = "moderator"
+	// Admins can do everything moderators can, and read the audit log.
+	roleAdmin = "admin"
+)
+
+// middlewareRequireModerator rejects callers who aren't moderators or admins.
+// It must run after middlewareValidateJWT. Moderators and admins are
+// appointed by setting users.role in the database.
+func (cfg *apiConfig) middlewareRequireModerator(next http.Handler) http.Handler {
+	return cfg.requireRole(next, "Moderator access required", roleModerator, roleAdmin)
+}
+
+// middlewareRequireAdmin rejects callers who aren't admins. It must run after
+// middlewareValidateJWT.
+func (cfg *apiConfig) middlewareRequireAdmin(next http.Handler) http.Handler {
+	return cfg.requireRole(next, "Admin access required", roleAdmin)
+}
+
+func (cfg *apiConfig) requireRole(next http.Handler, msg string, roles ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, err := cfg.db.GetUserByID(r.Context(), userIDFromContext(r.Context()))
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusInternalServerError, "Error checking permissions", err)
+			return
+		}
+		if err != nil || !slices.Contains(roles, u.Role) || u.DeletedAt.Valid {
+			respondWithError(w, http.StatusForbidden, msg, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handlerLabelChirp lets a moderator set or clear the content warning and
+// sensitive media flag on any chirp. Omitted fields are left unchanged.
+func (cfg *apiConfig) handlerLabelChirp(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		ContentWarning *string `json:"content_warning"`
+		SensitiveMedia *bool   `json:"sensitive_media"`
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirp_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID format", err)
+		return
+	}
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	c, err := cfg.db.GetChirpByID(r.Context(), chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error labeling chirp", err)
+		return
+	}
+	if params.ContentWarning != nil {
+		c.ContentWarning = strings.TrimSpace(*params.ContentWarning)
+		if len([]rune(c.ContentWarning)) > maxContentWarningLength {
+			respondWithError(w, http.StatusBadRequest, "Content warning must be at most 100 characters", nil)
+			return
+		}
+	}
+	if params.SensitiveMedia != nil {
+		c.SensitiveMedia = *params.SensitiveMedia
+	}
+	c, err = cfg.db.SetChirpLabels(r.Context(), database.SetChirpLabelsParams{
+		ID:             c.ID,
+		ContentWarning: c.ContentWarning,
+		SensitiveMedia: c.SensitiveMedia,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error labeling chirp", err)
+		return
+	}
+	cfg.audit(r, auditEntry{
+		Action:     auditChirpLabels,
+		ActorID:    userIDFromContext(r.Context()),
+		TargetType: auditTargetChirp,
+		TargetID:   c.ID,
+		Details:    fmt.Sprintf("content_warning=%q sensitive_media=%t", c.ContentWarning, c.SensitiveMedia),
+	})
+	chirp, err := cfg.chirpResponse(r.Context(), c, uuid.Nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error labeling chirp", err)
+		return
+	}
+	err = cfg.publishChirpEvent(r.Context(), c, "chirp.labeled", chirp, realtime.ThreadTopic(c.ID))
+	if err != nil {
+		loggerFromContext(r.Context()).Error("Error announcing chirp labels", "chirp_id", c.ID, "error", err)
+	}
+	respondWithJSON(w, http.StatusOK, chirp)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/kien-tn/chirpy/internal/database"
+)
+
+func (cfg *apiConfig) handlerGetNotifications(w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(r.Context())
+	cursor, limit, err := pageParams(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	rows, err := cfg.db.ListNotifications(r.Context(), database.ListNotificationsParams{
+		UserID:     userID,
+		CursorTime: cursor.Time,
+		CursorID:   cursor.ID,
+		PageSize:   limit,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting notifications", err)
+		return
+	}
+	unread, err := cfg.db.CountUnreadNotifications(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error counting notifications", err)
+		return
+	}
+	output := []Notification{}
+	for _, n := range rows {
+		output = append(output, notificationFromRow(n))
+	}
+	nextCursor := ""
+	if len(rows) == int(limit) {
+		last := rows[len(rows)-1]
+		nextCursor = encodeCursor(last.UpdatedAt, last.ID)
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"notifications": output,
+		"unread_count":  unread,
+		"next_cursor":   nextCursor,
+	})
+}
+
+func (cfg *apiConfig) handlerMarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	notificationID, err := uuid.Parse(r.PathValue("notification_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid notification ID format", err)
+		return
+	}
+	_, err = cfg.db.MarkNotificationRead(r.Context(), database.MarkNotificationReadParams{
+		ID:     notificationID,
+		UserID: userIDFromContext(r.Context()),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Notification not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating notification", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerMarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	n, err := cfg.db.MarkAllNotificationsRead(r.Context(), userIDFromContext(r.Context()))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating notifications", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"marked_read": n,
+	})
+}
+
+func (cfg *apiConfig) handlerGetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	stored, err := cfg.db.GetNotificationPreferences(r.Context(), userIDFromContext(r.Context()))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting notification preferences", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, notificationPreferences(stored))
+}
+
+func (cfg *apiConfig) handlerUpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	params := map[string]bool{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+	for t := range params {
+		if !validNotificationType(t) {
+			respondWithError(w, http.StatusBadRequest, "Unknown notification type: "+t, nil)
+			return
+		}
+	}
+	userID := userIDFromContext(r.Context())
+	for t, enabled := range params {
+		_, err := cfg.db.UpsertNotificationPreference(r.Context(), database.UpsertNotificationPreferenceParams{
+			UserID:  userID,
+			Type:    t,
+			Enabled: enabled,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error updating notification preferences", err)
+			return
+		}
+	}
+	stored, err := cfg.db.GetNotificationPreferences(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting notification preferences", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, notificationPreferences(stored))
+}
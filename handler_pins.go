@@ -0,0 +1,168 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/kien-tn/chirpy/internal/database"
+)
+
+const (
+	maxPinnedChirps    = 3
+	maxPinnedChirpsRed = 10
+)
+
+func (cfg *apiConfig) handlerPinChirp(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirp_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID format", err)
+		return
+	}
+	userID := userIDFromContext(r.Context())
+	c, err := cfg.db.GetChirpByID(r.Context(), chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Chirp not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error pinning chirp", err)
+		return
+	}
+	if c.UserID != userID {
+		respondWithError(w, http.StatusForbidden, "You can only pin your own chirps", nil)
+		return
+	}
+	// Pinning is idempotent, so a chirp that's already pinned doesn't need a
+	// free slot.
+	pinned, err := cfg.db.GetPinnedChirpIDs(r.Context(), []uuid.UUID{chirpID})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error pinning chirp", err)
+		return
+	}
+	if len(pinned) > 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	u, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error pinning chirp", err)
+		return
+	}
+	limit := int64(maxPinnedChirps)
+	if u.IsChirpyRed {
+		limit = maxPinnedChirpsRed
+	}
+	count, err := cfg.db.CountPinnedChirps(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error pinning chirp", err)
+		return
+	}
+	if count >= limit {
+		respondWithError(w, http.StatusConflict, "You have pinned the maximum number of chirps", nil)
+		return
+	}
+	_, err = cfg.db.PinChirp(r.Context(), database.PinChirpParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error pinning chirp", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerUnpinChirp(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirp_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID format", err)
+		return
+	}
+	n, err := cfg.db.UnpinChirp(r.Context(), database.UnpinChirpParams{
+		UserID:  userIDFromContext(r.Context()),
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error unpinning chirp", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, "Chirp is not pinned", nil)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerReorderPins sets the order of the caller's pinned chirps. The
+// request must list every pinned chirp exactly once.
+func (cfg *apiConfig) handlerReorderPins(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		ChirpIDs []uuid.UUID `json:"chirp_ids"`
+	}
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	userID := userIDFromContext(r.Context())
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error reordering pins", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTracedTx(tx)
+	pinned, err := qtx.GetPinnedChirpIDsByUserID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error reordering pins", err)
+		return
+	}
+	if len(params.ChirpIDs) != len(pinned) {
+		respondWithError(w, http.StatusBadRequest, "chirp_ids must list each pinned chirp once", nil)
+		return
+	}
+	for i, id := range params.ChirpIDs {
+		if !slices.Contains(pinned, id) || slices.Contains(params.ChirpIDs[:i], id) {
+			respondWithError(w, http.StatusBadRequest, "chirp_ids must list each pinned chirp once", nil)
+			return
+		}
+	}
+	for i, id := range params.ChirpIDs {
+		err := qtx.SetPinPosition(r.Context(), database.SetPinPositionParams{
+			UserID:   userID,
+			ChirpID:  id,
+			Position: int32(i),
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error reordering pins", err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error reordering pins", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// pinnedFirst moves the author's pinned chirps, in their pinned order, ahead
+// of the rest of chirps.
+func pinnedFirst(chirps []database.Chirp, pinnedIDs []uuid.UUID) []database.Chirp {
+	output := make([]database.Chirp, 0, len(chirps))
+	for _, id := range pinnedIDs {
+		i := slices.IndexFunc(chirps, func(c database.Chirp) bool { return c.ID == id })
+		if i >= 0 {
+			output = append(output, chirps[i])
+		}
+	}
+	for _, c := range chirps {
+		if !slices.Contains(pinnedIDs, c.ID) {
+			output = append(output, c)
+		}
+	}
+	return output
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/kien-tn/chirpy/internal/database"
+)
+
+func TestPinnedFirst(t *testing.T) {
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New(), uuid.New()}
+	chirps := make([]database.Chirp, len(ids))
+	for i, id := range ids {
+		chirps[i] = database.Chirp{ID: id}
+	}
+	tests := []struct {
+		name   string
+		pinned []uuid.UUID
+		want   []uuid.UUID
+	}{
+		{"no pins", nil, ids},
+		{"pins in chosen order", []uuid.UUID{ids[2], ids[0]}, []uuid.UUID{ids[2], ids[0], ids[1], ids[3]}},
+		{"pin not in the listing", []uuid.UUID{uuid.New(), ids[3]}, []uuid.UUID{ids[3], ids[0], ids[1], ids[2]}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []uuid.UUID
+			for _, c := range pinnedFirst(chirps, tt.pinned) {
+				got = append(got, c.ID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("pinnedFirst = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
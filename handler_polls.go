@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kien-tn/chirpy/internal/database"
+	"github.com/kien-tn/chirpy/internal/realtime"
+	"github.com/lib/pq"
+)
+
+const (
+	minPollOptions      = 2
+	maxPollOptions      = 4
+	maxPollOptionLength = 25
+	minPollDuration     = 5 * time.Minute
+	maxPollDuration     = 7 * 24 * time.Hour
+	pollBatchSize       = 100
+)
+
+// ChirpPoll is a poll as seen by one viewer. Vote counts are nil until the
+// viewer has voted or the poll has closed.
+type ChirpPoll struct {
+	ID             uuid.UUID    `json:"id"`
+	ClosesAt       time.Time    `json:"closes_at"`
+	Closed         bool         `json:"closed"`
+	ResultsVisible bool         `json:"results_visible"`
+	TotalVotes     *int64       `json:"total_votes"`
+	VotedOptionID  *uuid.UUID   `json:"voted_option_id"`
+	Options        []PollOption `json:"options"`
+}
+
+type PollOption struct {
+	ID    uuid.UUID `json:"id"`
+	Label string    `json:"label"`
+	Votes *int64    `json:"votes"`
+}
+
+type pollParams struct {
+	Options  []string  `json:"options"`
+	ClosesAt time.Time `json:"closes_at"`
+}
+
+// validatePoll trims the options in place and returns a message for the
+// client when the poll is invalid. opensAt is when the poll's chirp is
+// published.
+func validatePoll(p pollParams, opensAt time.Time) string {
+	if len(p.Options) < minPollOptions || len(p.Options) > maxPollOptions {
+		return "A poll must have 2 to 4 options"
+	}
+	for i, option := range p.Options {
+		option = strings.TrimSpace(option)
+		if option == "" || len([]rune(option)) > maxPollOptionLength {
+			return "Poll options must be 1-25 characters"
+		}
+		if slices.Contains(p.Options[:i], option) {
+			return "Poll options must be unique"
+		}
+		p.Options[i] = option
+	}
+	duration := p.ClosesAt.Sub(opensAt)
+	if duration < minPollDuration || duration > maxPollDuration {
+		return "A poll must close between 5 minutes and 7 days after it opens"
+	}
+	return ""
+}
+
+func insertPoll(ctx context.Context, qtx *database.Queries, chirpID uuid.UUID, p pollParams) error {
+	poll, err := qtx.CreatePoll(ctx, database.CreatePollParams{
+		ChirpID:  chirpID,
+		ClosesAt: p.ClosesAt.UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	for i, option := range p.Options {
+		err := qtx.CreatePollOption(ctx, database.CreatePollOptionParams{
+			PollID:   poll.ID,
+			Position: int32(i),
+			Label:    option,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chirpPolls loads the polls on the given chirps with live tallies, hiding
+// the results from a viewer who hasn't voted on a poll that is still open.
+func (cfg *apiConfig) chirpPolls(ctx context.Context, chirpIDs []uuid.UUID, viewerID uuid.UUID) (map[uuid.UUID]*ChirpPoll, error) {
+	polls, err := cfg.db.GetPollsByChirpIDs(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	byChirp := map[uuid.UUID]*ChirpPoll{}
+	if len(polls) == 0 {
+		return byChirp, nil
+	}
+	pollIDs := make([]uuid.UUID, 0, len(polls))
+	for _, p := range polls {
+		pollIDs = append(pollIDs, p.ID)
+	}
+	options, err := cfg.db.GetPollOptionsWithVotes(ctx, pollIDs)
+	if err != nil {
+		return nil, err
+	}
+	votes := map[uuid.UUID]uuid.UUID{}
+	if viewerID != uuid.Nil {
+		rows, err := cfg.db.GetUserPollVotes(ctx, database.GetUserPollVotesParams{
+			UserID:  viewerID,
+			PollIds: pollIDs,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			votes[row.PollID] = row.OptionID
+		}
+	}
+
+	byID := map[uuid.UUID]*ChirpPoll{}
+	now := time.Now()
+	for _, p := range polls {
+		poll := &ChirpPoll{
+			ID:       p.ID,
+			ClosesAt: p.ClosesAt,
+			Closed:   !p.ClosesAt.After(now),
+			Options:  []PollOption{},
+		}
+		if optionID, ok := votes[p.ID]; ok {
+			poll.VotedOptionID = &optionID
+		}
+		poll.ResultsVisible = poll.Closed || poll.VotedOptionID != nil
+		if poll.ResultsVisible {
+			poll.TotalVotes = new(int64)
+		}
+		byID[p.ID] = poll
+		byChirp[p.ChirpID] = poll
+	}
+	for _, o := range options {
+		poll := byID[o.PollID]
+		option := PollOption{ID: o.ID, Label: o.Label}
+		if poll.ResultsVisible {
+			option.Votes = &o.Votes
+			*poll.TotalVotes += o.Votes
+		}
+		poll.Options = append(poll.Options, option)
+	}
+	return byChirp, nil
+}
+
+func (cfg *apiConfig) handlerVotePoll(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		OptionID uuid.UUID `json:"option_id"`
+	}
+	pollID, err := uuid.Parse(r.PathValue("poll_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid poll ID format", err)
+		return
+	}
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	userID := userIDFromContext(r.Context())
+	poll, err := cfg.db.GetPollByID(r.Context(), pollID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Poll not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting poll", err)
+		return
+	}
+	chirp, err := cfg.db.GetVisibleChirpByID(r.Context(), database.GetVisibleChirpByIDParams{
+		ID:       poll.ChirpID,
+		ViewerID: userID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		// The chirp has been deleted.
+		respondWithError(w, http.StatusNotFound, "Poll not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting poll", err)
+		return
+	}
+	blockers, err := cfg.db.GetBlockerIDs(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting poll", err)
+		return
+	}
+	if slices.Contains(blockers, chirp.UserID) {
+		respondWithError(w, http.StatusNotFound, "Poll not found", nil)
+		return
+	}
+	if !poll.ClosesAt.After(time.Now()) {
+		respondWithError(w, http.StatusConflict, "Poll is closed", nil)
+		return
+	}
+
+	n, err := cfg.db.CreatePollVote(r.Context(), database.CreatePollVoteParams{
+		UserID:   userID,
+		OptionID: params.OptionID,
+		PollID:   pollID,
+	})
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		respondWithError(w, http.StatusBadRequest, "Invalid option ID", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error recording vote", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusConflict, "You have already voted in this poll", nil)
+		return
+	}
+	polls, err := cfg.chirpPolls(r.Context(), []uuid.UUID{chirp.ID}, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting poll", err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, polls[chirp.ID])
+}
+
+// closeEndedPolls tells authors their polls have ended and pushes the final
+// results to anyone watching the chirp. Polls are claimed with SKIP LOCKED,
+// so each author is notified once however many instances are running.
+func (cfg *apiConfig) closeEndedPolls(ctx context.Context) (int, error) {
+	ended, err := cfg.db.ClaimEndedPolls(ctx, pollBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	for _, p := range ended {
+		err := cfg.notify(ctx, notificationEvent{
+			UserID:  p.UserID,
+			Type:    notificationPollEnded,
+			ChirpID: p.ChirpID,
+		})
+		if err != nil {
+			slog.Error("Error notifying that a poll ended", "user_id", p.UserID, "poll_id", p.ID, "error", err)
+		}
+		polls, err := cfg.chirpPolls(ctx, []uuid.UUID{p.ChirpID}, uuid.Nil)
+		if err != nil {
+			slog.Error("Error getting poll results", "poll_id", p.ID, "error", err)
+			continue
+		}
+		cfg.hub.Publish(realtime.ThreadTopic(p.ChirpID), "poll.closed", polls[p.ChirpID])
+	}
+	return len(ended), nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidatePoll(t *testing.T) {
+	now := time.Now()
+	publishAt := now.Add(48 * time.Hour)
+	tests := []struct {
+		name    string
+		options []string
+		closes  time.Time
+		opens   time.Time
+		want    string
+	}{
+		{"valid", []string{"Yes", "No"}, now.Add(time.Hour), now, ""},
+		{"too few options", []string{"Yes"}, now.Add(time.Hour), now, "A poll must have 2 to 4 options"},
+		{"too many options", []string{"a", "b", "c", "d", "e"}, now.Add(time.Hour), now, "A poll must have 2 to 4 options"},
+		{"blank option", []string{"Yes", "  "}, now.Add(time.Hour), now, "Poll options must be 1-25 characters"},
+		{"long option", []string{"Yes", "This option is far too long to fit"}, now.Add(time.Hour), now, "Poll options must be 1-25 characters"},
+		{"duplicate after trimming", []string{"Yes", " Yes "}, now.Add(time.Hour), now, "Poll options must be unique"},
+		{"closes too soon", []string{"Yes", "No"}, now.Add(time.Minute), now, "A poll must close between 5 minutes and 7 days after it opens"},
+		{"closes too late", []string{"Yes", "No"}, now.Add(8 * 24 * time.Hour), now, "A poll must close between 5 minutes and 7 days after it opens"},
+		{"scheduled", []string{"Yes", "No"}, publishAt.Add(time.Hour), publishAt, ""},
+		{"scheduled closes before publishing", []string{"Yes", "No"}, now.Add(time.Hour), publishAt, "A poll must close between 5 minutes and 7 days after it opens"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := validatePoll(pollParams{Options: tt.options, ClosesAt: tt.closes}, tt.opens)
+			if got != tt.want {
+				t.Errorf("validatePoll = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePollTrimsOptions(t *testing.T) {
+	p := pollParams{Options: []string{" Yes", "No "}, ClosesAt: time.Now().Add(time.Hour)}
+	if msg := validatePoll(p, time.Now()); msg != "" {
+		t.Fatalf("validatePoll = %q, want no error", msg)
+	}
+	if p.Options[0] != "Yes" || p.Options[1] != "No" {
+		t.Errorf("options = %q, want them trimmed", p.Options)
+	}
+}
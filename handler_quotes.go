@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kien-tn/chirpy/internal/database"
+)
+
+// QuotedChirp is the original embedded in a quote chirp. When the original
+// has been deleted, or its author has blocked the viewer, only the ID is
+// returned with Available set to false.
+type QuotedChirp struct {
+	ID        uuid.UUID    `json:"id"`
+	Available bool         `json:"available"`
+	UserID    *uuid.UUID   `json:"user_id,omitempty"`
+	Author    *ChirpAuthor `json:"author,omitempty"`
+	Body      string       `json:"body,omitempty"`
+	CreatedAt *time.Time   `json:"created_at,omitempty"`
+}
+
+// quotedOriginals loads the chirps quoted by chirps that viewerID may see,
+// keyed by ID. Originals missing from the map are unavailable.
+func (cfg *apiConfig) quotedOriginals(ctx context.Context, chirps []database.Chirp, viewerID uuid.UUID) (map[uuid.UUID]database.Chirp, error) {
+	originals := map[uuid.UUID]database.Chirp{}
+	ids := []uuid.UUID{}
+	for _, c := range chirps {
+		if c.QuotedChirpID.Valid && !slices.Contains(ids, c.QuotedChirpID.UUID) {
+			ids = append(ids, c.QuotedChirpID.UUID)
+		}
+	}
+	if len(ids) == 0 {
+		return originals, nil
+	}
+	rows, err := cfg.db.GetChirpsByIDs(ctx, database.GetChirpsByIDsParams{
+		Ids:      ids,
+		ViewerID: viewerID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var blockers []uuid.UUID
+	if viewerID != uuid.Nil {
+		blockers, err = cfg.db.GetBlockerIDs(ctx, viewerID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, c := range rows {
+		if !slices.Contains(blockers, c.UserID) {
+			originals[c.ID] = c
+		}
+	}
+	return originals, nil
+}
+
+// canQuote reports whether userID may quote the chirp: it must be visible to
+// them, and its author mustn't have blocked them.
+func (cfg *apiConfig) canQuote(ctx context.Context, userID, chirpID uuid.UUID) (bool, error) {
+	original, err := cfg.db.GetVisibleChirpByID(ctx, database.GetVisibleChirpByIDParams{
+		ID:       chirpID,
+		ViewerID: userID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	blockers, err := cfg.db.GetBlockerIDs(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return !slices.Contains(blockers, original.UserID), nil
+}
+
+func quotedChirp(c database.Chirp, originals map[uuid.UUID]database.Chirp, authors map[uuid.UUID]ChirpAuthor) *QuotedChirp {
+	if !c.QuotedChirpID.Valid {
+		return nil
+	}
+	original, ok := originals[c.QuotedChirpID.UUID]
+	if !ok {
+		return &QuotedChirp{ID: c.QuotedChirpID.UUID}
+	}
+	author := authors[original.UserID]
+	return &QuotedChirp{
+		ID:        original.ID,
+		Available: true,
+		UserID:    &original.UserID,
+		Author:    &author,
+		Body:      original.Body,
+		CreatedAt: &original.CreatedAt,
+	}
+}
+
+// notifyQuote tells the author of the quoted chirp about the quote, unless
+// there is a block between them.
+func (cfg *apiConfig) notifyQuote(ctx context.Context, c database.Chirp, q *QuotedChirp) error {
+	blocked, err := cfg.db.BlockExistsBetween(ctx, database.BlockExistsBetweenParams{
+		UserID:   c.UserID,
+		OtherIds: []uuid.UUID{*q.UserID},
+	})
+	if err != nil || blocked {
+		return err
+	}
+	return cfg.notify(ctx, notificationEvent{
+		UserID:  *q.UserID,
+		ActorID: c.UserID,
+		Type:    notificationQuote,
+		ChirpID: q.ID,
+	})
+}
@@ -0,0 +1,587 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kien-tn/chirpy/internal/database"
+	"github.com/kien-tn/chirpy/internal/realtime"
+)
+
+const (
+	reportTargetChirp = "chirp"
+	reportTargetUser  = "user"
+
+	reportSourceUser       = "user"
+	reportSourceSpamFilter = "spam_filter"
+
+	reportStatusOpen      = "open"
+	reportStatusInReview  = "in_review"
+	reportStatusResolved  = "resolved"
+	reportStatusDismissed = "dismissed"
+
+	resolutionDismiss     = "dismiss"
+	resolutionRemoveChirp = "remove_chirp"
+	resolutionSuspendUser = "suspend_user"
+
+	maxReportDetailsLength = 500
+)
+
+var reportReasons = []string{"spam", "harassment", "hate", "violence", "sexual", "self_harm", "impersonation", "other"}
+
+// reportTransitions lists the statuses a moderator can move a report to
+// without resolving it. Resolved and dismissed reports are final.
+var reportTransitions = map[string][]string{
+	reportStatusOpen:     {reportStatusInReview},
+	reportStatusInReview: {reportStatusOpen},
+}
+
+// Report is a report as its reporter sees it.
+type Report struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	TargetType string     `json:"target_type"`
+	ChirpID    *uuid.UUID `json:"chirp_id,omitempty"`
+	UserID     *uuid.UUID `json:"user_id,omitempty"`
+	Reason     string     `json:"reason"`
+	Details    string     `json:"details"`
+	Status     string     `json:"status"`
+}
+
+// ModerationReport is a report in the moderator queue.
+type ModerationReport struct {
+	Report
+	UpdatedAt  time.Time          `json:"updated_at"`
+	Source     string             `json:"source"`
+	ReporterID *uuid.UUID         `json:"reporter_id"`
+	ChirpBody  string             `json:"chirp_body,omitempty"`
+	AssigneeID *uuid.UUID         `json:"assignee_id"`
+	Resolution *string            `json:"resolution"`
+	ResolvedAt *time.Time         `json:"resolved_at"`
+	Actions    []ModerationAction `json:"actions,omitempty"`
+}
+
+type ModerationAction struct {
+	ID          uuid.UUID  `json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ModeratorID uuid.UUID  `json:"moderator_id"`
+	Action      string     `json:"action"`
+	FromStatus  string     `json:"from_status"`
+	ToStatus    string     `json:"to_status"`
+	AssigneeID  *uuid.UUID `json:"assignee_id,omitempty"`
+	Note        string     `json:"note"`
+}
+
+func nullableUUID(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	return &id.UUID
+}
+
+func reportResponse(r database.Report) Report {
+	return Report{
+		ID:         r.ID,
+		CreatedAt:  r.CreatedAt,
+		TargetType: r.TargetType,
+		ChirpID:    nullableUUID(r.ChirpID),
+		UserID:     nullableUUID(r.UserID),
+		Reason:     r.Reason,
+		Details:    r.Details,
+		Status:     r.Status,
+	}
+}
+
+func moderationReportResponse(r database.Report) ModerationReport {
+	out := ModerationReport{
+		Report:     reportResponse(r),
+		UpdatedAt:  r.UpdatedAt,
+		Source:     r.Source,
+		ReporterID: nullableUUID(r.ReporterID),
+		ChirpBody:  r.ChirpBody,
+		AssigneeID: nullableUUID(r.AssigneeID),
+	}
+	if r.Resolution.Valid {
+		out.Resolution = &r.Resolution.String
+	}
+	if r.ResolvedAt.Valid {
+		out.ResolvedAt = &r.ResolvedAt.Time
+	}
+	return out
+}
+
+func (cfg *apiConfig) handlerCreateReport(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		ChirpID *uuid.UUID `json:"chirp_id"`
+		UserID  *uuid.UUID `json:"user_id"`
+		Reason  string     `json:"reason"`
+		Details string     `json:"details"`
+	}
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	if (params.ChirpID == nil) == (params.UserID == nil) {
+		respondWithError(w, http.StatusBadRequest, "Report either a chirp_id or a user_id", nil)
+		return
+	}
+	if !slices.Contains(reportReasons, params.Reason) {
+		respondWithError(w, http.StatusBadRequest, "Reason must be one of "+strings.Join(reportReasons, ", "), nil)
+		return
+	}
+	params.Details = strings.TrimSpace(params.Details)
+	if len([]rune(params.Details)) > maxReportDetailsLength {
+		respondWithError(w, http.StatusBadRequest, "Details must be at most 500 characters", nil)
+		return
+	}
+	reporterID := userIDFromContext(r.Context())
+	report := database.CreateReportParams{
+		ReporterID: uuid.NullUUID{UUID: reporterID, Valid: true},
+		Reason:     params.Reason,
+		Details:    params.Details,
+		Source:     reportSourceUser,
+	}
+	if params.ChirpID != nil {
+		c, err := cfg.db.GetVisibleChirpByID(r.Context(), database.GetVisibleChirpByIDParams{
+			ID:       *params.ChirpID,
+			ViewerID: reporterID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, "Chirp not found", err)
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error creating report", err)
+			return
+		}
+		report.TargetType = reportTargetChirp
+		report.ChirpID = uuid.NullUUID{UUID: c.ID, Valid: true}
+		report.UserID = uuid.NullUUID{UUID: c.UserID, Valid: true}
+		report.ChirpBody = c.Body
+	} else {
+		u, err := cfg.db.GetUserByID(r.Context(), *params.UserID)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && u.DeletedAt.Valid) {
+			respondWithError(w, http.StatusNotFound, "User not found", err)
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error creating report", err)
+			return
+		}
+		report.TargetType = reportTargetUser
+		report.UserID = uuid.NullUUID{UUID: u.ID, Valid: true}
+	}
+	if report.UserID.UUID == reporterID {
+		respondWithError(w, http.StatusBadRequest, "You can't report yourself", nil)
+		return
+	}
+	created, err := cfg.db.CreateReport(r.Context(), report)
+	if isUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "You have already reported this", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating report", err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, reportResponse(created))
+}
+
+// handlerGetReports is the moderation queue, newest first. It can be
+// filtered by status and by assignee_id.
+func (cfg *apiConfig) handlerGetReports(w http.ResponseWriter, r *http.Request) {
+	cursor, limit, err := pageParams(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	status := r.URL.Query().Get("status")
+	assigneeID := uuid.Nil
+	if s := r.URL.Query().Get("assignee_id"); s != "" {
+		assigneeID, err = uuid.Parse(s)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid assignee ID format", err)
+			return
+		}
+	}
+	rows, err := cfg.db.ListReports(r.Context(), database.ListReportsParams{
+		Status:     status,
+		AssigneeID: assigneeID,
+		CursorTime: cursor.Time,
+		CursorID:   cursor.ID,
+		PageSize:   limit,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting reports", err)
+		return
+	}
+	output := []ModerationReport{}
+	for _, report := range rows {
+		output = append(output, moderationReportResponse(report))
+	}
+	nextCursor := ""
+	if len(rows) == int(limit) {
+		last := rows[len(rows)-1]
+		nextCursor = encodeCursor(last.CreatedAt, last.ID)
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"reports":     output,
+		"next_cursor": nextCursor,
+	})
+}
+
+func (cfg *apiConfig) handlerGetReport(w http.ResponseWriter, r *http.Request) {
+	reportID, err := uuid.Parse(r.PathValue("report_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid report ID format", err)
+		return
+	}
+	report, err := cfg.db.GetReportByID(r.Context(), reportID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Report not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting report", err)
+		return
+	}
+	output, err := cfg.moderationReportWithActions(r.Context(), report)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting report", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, output)
+}
+
+func (cfg *apiConfig) moderationReportWithActions(ctx context.Context, report database.Report) (ModerationReport, error) {
+	actions, err := cfg.db.GetModerationActionsByReportID(ctx, report.ID)
+	if err != nil {
+		return ModerationReport{}, err
+	}
+	output := moderationReportResponse(report)
+	output.Actions = []ModerationAction{}
+	for _, a := range actions {
+		output.Actions = append(output.Actions, ModerationAction{
+			ID:          a.ID,
+			CreatedAt:   a.CreatedAt,
+			ModeratorID: a.ModeratorID,
+			Action:      a.Action,
+			FromStatus:  a.FromStatus,
+			ToStatus:    a.ToStatus,
+			AssigneeID:  nullableUUID(a.AssigneeID),
+			Note:        a.Note,
+		})
+	}
+	return output, nil
+}
+
+// reportChange is one moderator action on a report: the report's new state
+// and the entry recorded for it in moderation_actions.
+type reportChange struct {
+	Action     string
+	Status     string
+	AssigneeID uuid.NullUUID
+	Resolution sql.NullString
+	Note       string
+}
+
+// reportError rejects a moderator action with a client error.
+type reportError struct {
+	code int
+	msg  string
+}
+
+func (e reportError) Error() string {
+	return e.msg
+}
+
+// moderateReport locks the {report_id} report, lets decide work out the
+// change (running any side effects with qtx), then saves the report and
+// records the action in the same transaction.
+func (cfg *apiConfig) moderateReport(w http.ResponseWriter, r *http.Request, decide func(qtx *database.Queries, report database.Report) (reportChange, error)) (database.Report, bool) {
+	reportID, err := uuid.Parse(r.PathValue("report_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid report ID format", err)
+		return database.Report{}, false
+	}
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating report", err)
+		return database.Report{}, false
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTracedTx(tx)
+	report, err := qtx.LockReport(r.Context(), reportID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Report not found", err)
+		return database.Report{}, false
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating report", err)
+		return database.Report{}, false
+	}
+	change, err := decide(qtx, report)
+	var reportErr reportError
+	if errors.As(err, &reportErr) {
+		respondWithError(w, reportErr.code, reportErr.msg, nil)
+		return database.Report{}, false
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating report", err)
+		return database.Report{}, false
+	}
+	updated, err := qtx.UpdateReport(r.Context(), database.UpdateReportParams{
+		ID:         report.ID,
+		Status:     change.Status,
+		AssigneeID: change.AssigneeID,
+		Resolution: change.Resolution,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating report", err)
+		return database.Report{}, false
+	}
+	_, err = qtx.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+		ReportID:    report.ID,
+		ModeratorID: userIDFromContext(r.Context()),
+		Action:      change.Action,
+		FromStatus:  report.Status,
+		ToStatus:    change.Status,
+		AssigneeID:  change.AssigneeID,
+		Note:        change.Note,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating report", err)
+		return database.Report{}, false
+	}
+	err = recordAudit(r, qtx, auditEntry{
+		Action:     auditReportPrefix + change.Action,
+		ActorID:    userIDFromContext(r.Context()),
+		TargetType: auditTargetReport,
+		TargetID:   report.ID,
+		Details:    report.Status + " -> " + change.Status,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating report", err)
+		return database.Report{}, false
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating report", err)
+		return database.Report{}, false
+	}
+	return updated, true
+}
+
+func (cfg *apiConfig) respondWithModerationReport(w http.ResponseWriter, r *http.Request, report database.Report) {
+	output, err := cfg.moderationReportWithActions(r.Context(), report)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting report", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, output)
+}
+
+func isFinalReportStatus(status string) bool {
+	return status == reportStatusResolved || status == reportStatusDismissed
+}
+
+// canMoveReport reports whether a moderator can move a report from one
+// status to another without resolving it.
+func canMoveReport(from, to string) bool {
+	return slices.Contains(reportTransitions[from], to)
+}
+
+// canWorkReports reports whether a user with role can be assigned reports.
+// It matches the roles let through middlewareRequireModerator.
+func canWorkReports(role string) bool {
+	return slices.Contains([]string{roleModerator, roleAdmin}, role)
+}
+
+// releasesHold reports whether dismissing report publishes its chirp. Only
+// the spam filter's own report does; dismissing a user's report on a held
+// chirp leaves the hold alone, even once the reporter has been deleted.
+func releasesHold(report database.Report) bool {
+	return report.ChirpID.Valid && report.Source == reportSourceSpamFilter
+}
+
+// handlerAssignReport assigns a report to a moderator, the caller by
+// default, and moves an open report into review.
+func (cfg *apiConfig) handlerAssignReport(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		AssigneeID *uuid.UUID `json:"assignee_id"`
+		Note       string     `json:"note"`
+	}
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	assigneeID := userIDFromContext(r.Context())
+	if params.AssigneeID != nil {
+		assigneeID = *params.AssigneeID
+	}
+	report, ok := cfg.moderateReport(w, r, func(qtx *database.Queries, report database.Report) (reportChange, error) {
+		if isFinalReportStatus(report.Status) {
+			return reportChange{}, reportError{http.StatusConflict, "Report is already closed"}
+		}
+		assignee, err := qtx.GetUserByID(r.Context(), assigneeID)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && !canWorkReports(assignee.Role)) {
+			return reportChange{}, reportError{http.StatusBadRequest, "Assignee must be a moderator"}
+		}
+		if err != nil {
+			return reportChange{}, err
+		}
+		return reportChange{
+			Action:     "assign",
+			Status:     reportStatusInReview,
+			AssigneeID: uuid.NullUUID{UUID: assigneeID, Valid: true},
+			Note:       params.Note,
+		}, nil
+	})
+	if !ok {
+		return
+	}
+	cfg.respondWithModerationReport(w, r, report)
+}
+
+// handlerSetReportStatus moves a report between open and in_review. Closing
+// a report goes through handlerResolveReport.
+func (cfg *apiConfig) handlerSetReportStatus(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Status string `json:"status"`
+		Note   string `json:"note"`
+	}
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	report, ok := cfg.moderateReport(w, r, func(qtx *database.Queries, report database.Report) (reportChange, error) {
+		if !canMoveReport(report.Status, params.Status) {
+			return reportChange{}, reportError{http.StatusConflict, "Can't move a " + report.Status + " report to " + params.Status}
+		}
+		change := reportChange{
+			Action:     "status",
+			Status:     params.Status,
+			AssigneeID: report.AssigneeID,
+			Note:       params.Note,
+		}
+		if params.Status == reportStatusOpen {
+			// Reopening returns the report to the shared queue.
+			change.AssigneeID = uuid.NullUUID{}
+		}
+		return change, nil
+	})
+	if !ok {
+		return
+	}
+	cfg.respondWithModerationReport(w, r, report)
+}
+
+// handlerResolveReport closes a report with one of the resolution actions:
+// dismiss it, remove the reported chirp, or suspend the reported user, until
+// expires_at if given.
+func (cfg *apiConfig) handlerResolveReport(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Action    string     `json:"action"`
+		Note      string     `json:"note"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	var released *database.Chirp
+	report, ok := cfg.moderateReport(w, r, func(qtx *database.Queries, report database.Report) (reportChange, error) {
+		if isFinalReportStatus(report.Status) {
+			return reportChange{}, reportError{http.StatusConflict, "Report is already closed"}
+		}
+		change := reportChange{
+			Action:     params.Action,
+			Status:     reportStatusResolved,
+			AssigneeID: report.AssigneeID,
+			Resolution: sql.NullString{String: params.Action, Valid: true},
+			Note:       params.Note,
+		}
+		switch params.Action {
+		case resolutionDismiss:
+			change.Status = reportStatusDismissed
+			if releasesHold(report) {
+				c, err := qtx.ReleaseChirp(r.Context(), report.ChirpID.UUID)
+				if err != nil && !errors.Is(err, sql.ErrNoRows) {
+					return reportChange{}, err
+				}
+				if err == nil {
+					released = &c
+				}
+			}
+		case resolutionRemoveChirp:
+			if !report.ChirpID.Valid {
+				return reportChange{}, reportError{http.StatusBadRequest, "Only chirp reports can remove a chirp"}
+			}
+			if _, err := qtx.RemoveChirp(r.Context(), report.ChirpID.UUID); err != nil {
+				return reportChange{}, err
+			}
+			_, err := qtx.UnpinChirp(r.Context(), database.UnpinChirpParams{
+				UserID:  report.UserID.UUID,
+				ChirpID: report.ChirpID.UUID,
+			})
+			if err != nil {
+				return reportChange{}, err
+			}
+		case resolutionSuspendUser:
+			if !report.UserID.Valid {
+				// The reported account has been purged.
+				return reportChange{}, reportError{http.StatusConflict, "The reported user no longer exists"}
+			}
+			expiresAt := sql.NullTime{}
+			if params.ExpiresAt != nil {
+				if !params.ExpiresAt.After(time.Now()) {
+					return reportChange{}, reportError{http.StatusBadRequest, "expires_at must be in the future"}
+				}
+				expiresAt = sql.NullTime{Time: params.ExpiresAt.UTC(), Valid: true}
+			}
+			_, err := qtx.SetAccountState(r.Context(), database.SetAccountStateParams{
+				ID:                    report.UserID.UUID,
+				AccountState:          accountSuspended,
+				AccountStateExpiresAt: expiresAt,
+				AccountStateReason:    "Report " + report.ID.String(),
+			})
+			if err != nil {
+				return reportChange{}, err
+			}
+			if err := qtx.RevokeUserRefreshTokens(r.Context(), report.UserID.UUID); err != nil {
+				return reportChange{}, err
+			}
+		default:
+			return reportChange{}, reportError{http.StatusBadRequest, "Action must be dismiss, remove_chirp or suspend_user"}
+		}
+		return change, nil
+	})
+	if !ok {
+		return
+	}
+	if report.Resolution.String == resolutionRemoveChirp {
+		removed := map[string]interface{}{"id": report.ChirpID.UUID}
+		cfg.hub.Publish(realtime.TopicTimeline, "chirp.deleted", removed)
+		cfg.hub.Publish(realtime.TimelineTopic(report.UserID.UUID), "chirp.deleted", removed)
+		cfg.hub.Publish(realtime.ThreadTopic(report.ChirpID.UUID), "chirp.deleted", removed)
+	}
+	if report.Resolution.String == resolutionSuspendUser {
+		cfg.hub.DisconnectUser(report.UserID.UUID)
+	}
+	if released != nil {
+		if err := cfg.announceReleasedChirp(r.Context(), *released); err != nil {
+			loggerFromContext(r.Context()).Error("Error announcing released chirp", "chirp_id", released.ID, "error", err)
+		}
+	}
+	cfg.respondWithModerationReport(w, r, report)
+}
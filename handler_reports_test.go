@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/kien-tn/chirpy/internal/database"
+)
+
+func TestCanMoveReport(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{reportStatusOpen, reportStatusInReview, true},
+		{reportStatusInReview, reportStatusOpen, true},
+		{reportStatusOpen, reportStatusOpen, false},
+		{reportStatusOpen, reportStatusResolved, false},
+		{reportStatusInReview, reportStatusDismissed, false},
+		{reportStatusResolved, reportStatusOpen, false},
+		{reportStatusDismissed, reportStatusInReview, false},
+	}
+	for _, tt := range tests {
+		if got := canMoveReport(tt.from, tt.to); got != tt.want {
+			t.Errorf("canMoveReport(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestCanWorkReports(t *testing.T) {
+	tests := []struct {
+		role string
+		want bool
+	}{
+		{roleModerator, true},
+		{roleAdmin, true},
+		{"", false},
+		{"user", false},
+	}
+	for _, tt := range tests {
+		if got := canWorkReports(tt.role); got != tt.want {
+			t.Errorf("canWorkReports(%q) = %v, want %v", tt.role, got, tt.want)
+		}
+	}
+}
+
+func TestReleasesHold(t *testing.T) {
+	chirpID := uuid.NullUUID{UUID: uuid.New(), Valid: true}
+	reporterID := uuid.NullUUID{UUID: uuid.New(), Valid: true}
+	tests := []struct {
+		name   string
+		report database.Report
+		want   bool
+	}{
+		{"spam filter report", database.Report{ChirpID: chirpID, Reason: "spam", Source: reportSourceSpamFilter}, true},
+		{"user spam report", database.Report{ChirpID: chirpID, ReporterID: reporterID, Reason: "spam", Source: reportSourceUser}, false},
+		{"user harassment report", database.Report{ChirpID: chirpID, ReporterID: reporterID, Reason: "harassment", Source: reportSourceUser}, false},
+		{"deleted reporter's spam report", database.Report{ChirpID: chirpID, Reason: "spam", Source: reportSourceUser}, false},
+		{"user report", database.Report{ReporterID: reporterID, Reason: "spam", Source: reportSourceUser}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := releasesHold(tt.report); got != tt.want {
+				t.Errorf("releasesHold = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kien-tn/chirpy/internal/auth"
+	"github.com/kien-tn/chirpy/internal/database"
+	"github.com/kien-tn/chirpy/internal/realtime"
+)
+
+// trashRetentionDays is how long deleted chirps and accounts can be restored
+// before the purge job removes them for good.
+const trashRetentionDays = 30
+
+type TrashedChirp struct {
+	Chirp
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"`
+}
+
+func (cfg *apiConfig) handlerGetTrash(w http.ResponseWriter, r *http.Request) {
+	userID := userIDFromContext(r.Context())
+	chirps, err := cfg.db.GetDeletedChirpsByUserID(r.Context(), database.GetDeletedChirpsByUserIDParams{
+		UserID:        userID,
+		RetentionDays: trashRetentionDays,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting trash", err)
+		return
+	}
+	responses, err := cfg.chirpResponses(r.Context(), chirps, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting trash", err)
+		return
+	}
+	output := make([]TrashedChirp, 0, len(chirps))
+	for i, c := range chirps {
+		output = append(output, TrashedChirp{
+			Chirp:     responses[i],
+			DeletedAt: c.DeletedAt.Time,
+			PurgeAt:   c.DeletedAt.Time.AddDate(0, 0, trashRetentionDays),
+		})
+	}
+	respondWithJSON(w, http.StatusOK, output)
+}
+
+func (cfg *apiConfig) handlerRestoreChirp(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirp_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID format", err)
+		return
+	}
+	userID := userIDFromContext(r.Context())
+	c, err := cfg.db.RestoreChirp(r.Context(), database.RestoreChirpParams{
+		ID:            chirpID,
+		UserID:        userID,
+		RetentionDays: trashRetentionDays,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Chirp not found in trash", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error restoring chirp", err)
+		return
+	}
+	chirp, err := cfg.chirpResponse(r.Context(), c, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error restoring chirp", err)
+		return
+	}
+	err = cfg.publishChirpEvent(r.Context(), c, "chirp.restored", chirp,
+		realtime.TopicTimeline, realtime.TimelineTopic(userID))
+	if err != nil {
+		loggerFromContext(r.Context()).Error("Error announcing restored chirp", "chirp_id", c.ID, "error", err)
+	}
+	respondWithJSON(w, http.StatusOK, chirp)
+}
+
+// handlerDeleteAccount soft-deletes the caller's account once they confirm
+// their password. Their chirps and profile disappear immediately, and every
+// session is signed out. The account can be restored with
+// POST /api/users/restore until it is purged at purge_at.
+func (cfg *apiConfig) handlerDeleteAccount(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Password string `json:"password"`
+	}
+	type response struct {
+		PurgeAt time.Time `json:"purge_at"`
+	}
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	userID := userIDFromContext(r.Context())
+	u, err := cfg.db.GetUserByID(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Account not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error deleting account", err)
+		return
+	}
+	if err := auth.CheckPasswordHash(u.HashedPassword, params.Password); err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Incorrect password", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error deleting account", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTracedTx(tx)
+	n, err := qtx.SoftDeleteUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error deleting account", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, "Account not found", nil)
+		return
+	}
+	if err := qtx.RevokeUserRefreshTokens(r.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error deleting account", err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error deleting account", err)
+		return
+	}
+	respondWithJSON(w, http.StatusAccepted, response{
+		PurgeAt: time.Now().AddDate(0, 0, trashRetentionDays),
+	})
+}
+
+func (cfg *apiConfig) handlerRestoreAccount(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	u, err := cfg.db.GetDeletedUserByEmail(r.Context(), database.GetDeletedUserByEmailParams{
+		Email:         params.Email,
+		RetentionDays: trashRetentionDays,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error restoring account", err)
+		return
+	}
+	if err := auth.CheckPasswordHash(u.HashedPassword, params.Password); err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password", err)
+		return
+	}
+	u, err = cfg.db.RestoreUser(r.Context(), u.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error restoring account", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, User{
+		ID:          u.ID,
+		CreatedAt:   u.CreatedAt,
+		UpdatedAt:   u.UpdatedAt,
+		Email:       u.Email,
+		Handle:      u.Handle.String,
+		IsChirpyRed: u.IsChirpyRed,
+	})
+}
+
+// purgeTrash permanently removes chirps and accounts deleted longer ago than
+// the retention window, along with the stored files of their media.
+func (cfg *apiConfig) purgeTrash(ctx context.Context) (int, error) {
+	chirps, err := cfg.db.PurgeDeletedChirps(ctx, trashRetentionDays)
+	if err != nil {
+		return 0, err
+	}
+	purged := map[uuid.UUID]bool{}
+	for _, row := range chirps {
+		purged[row.ID] = true
+		cfg.deleteMediaBlobs(ctx, row.StorageKey, row.ThumbnailKey)
+	}
+	users, err := cfg.db.PurgeDeletedUsers(ctx, trashRetentionDays)
+	if err != nil {
+		return len(purged), err
+	}
+	for _, row := range users {
+		purged[row.ID] = true
+		cfg.deleteMediaBlobs(ctx, row.StorageKey, row.ThumbnailKey)
+	}
+	return len(purged), nil
+}
+
+func (cfg *apiConfig) deleteMediaBlobs(ctx context.Context, keys ...sql.NullString) {
+	for _, key := range keys {
+		if !key.Valid {
+			continue
+		}
+		if err := cfg.blobs.Delete(ctx, key.String); err != nil {
+			loggerFromContext(ctx).Error("Error deleting media", "key", key.String, "error", err)
+		}
+	}
+}
@@ -1,16 +1,21 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
-	"log"
+	"errors"
 	"net/http"
-	"os"
+	"net/url"
+	"regexp"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
-	"github.com/joho/godotenv"
 	"github.com/kien-tn/chirpy/internal/auth"
 	"github.com/kien-tn/chirpy/internal/database"
+	"github.com/lib/pq"
 )
 
 type User struct {
@@ -18,22 +23,53 @@ type User struct {
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 	Email        string    `json:"email"`
+	Handle       string    `json:"handle"`
 	Token        string    `json:"token"`
 	RefreshToken string    `json:"refresh_token"`
 	IsChirpyRed  bool      `json:"is_chirpy_red"`
 }
 
+type Profile struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	Handle      string    `json:"handle"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	Location    string    `json:"location"`
+	AvatarURL   string    `json:"avatar_url"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
+	ChirpCount  int64     `json:"chirp_count"`
+}
+
+var handlePattern = regexp.MustCompile(`^[A-Za-z0-9_]{1,15}$`)
+
+const invalidHandleMsg = "Handle must be 1-15 letters, digits or underscores"
+
+// isUniqueViolation reports whether err is a Postgres unique constraint error.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// isForeignKeyViolation reports whether err is a Postgres foreign key
+// constraint error, such as a reference to a user that doesn't exist.
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
+
 func handlerUsers(apiCfg *apiConfig, w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
 		Password string `json:"password"`
 		Email    string `json:"email"`
+		Handle   string `json:"handle"`
 	}
 	defer r.Body.Close()
 	decoder := json.NewDecoder(r.Body)
 	params := parameters{}
 	err := decoder.Decode(&params)
 	if err != nil {
-		log.Printf("Error decoding parameters: %s", err)
+		loggerFromContext(r.Context()).Info("Error decoding parameters", "error", err)
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte(`{"error": "Invalid request payload"}`))
 		return
@@ -43,6 +79,12 @@ func handlerUsers(apiCfg *apiConfig, w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(`{"error": "Email and password are required"}`))
 		return
 	}
+	if params.Handle != "" {
+		if !handlePattern.MatchString(params.Handle) {
+			respondWithError(w, http.StatusBadRequest, invalidHandleMsg, nil)
+			return
+		}
+	}
 	hashedPass, err := auth.HashPassword(params.Password)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error hashing password", err)
@@ -52,9 +94,14 @@ func handlerUsers(apiCfg *apiConfig, w http.ResponseWriter, r *http.Request) {
 	u, err := apiCfg.db.CreateUser(r.Context(), database.CreateUserParams{
 		Email:          params.Email,
 		HashedPassword: hashedPass,
+		Handle:         sql.NullString{String: params.Handle, Valid: params.Handle != ""},
 	})
+	if isUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "Email or handle is already taken", err)
+		return
+	}
 	if err != nil {
-		log.Printf("Error creating user: %s", err)
+		loggerFromContext(r.Context()).Error("Error creating user", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte(`{"error": "Something went wrong"}`))
 		return
@@ -66,25 +113,26 @@ func handlerUsers(apiCfg *apiConfig, w http.ResponseWriter, r *http.Request) {
 		"created_at":    u.CreatedAt,
 		"updated_at":    u.UpdatedAt,
 		"email":         u.Email,
+		"handle":        u.Handle.String,
 		"is_chirpy_red": u.IsChirpyRed,
 	})
 }
 
 func handlerUsersReset(apiCfg *apiConfig, w http.ResponseWriter, r *http.Request) {
-	godotenv.Load()
-	env := os.Getenv("PLATFORM")
-	if env != "dev" {
+	if apiCfg.platform != "dev" {
+		apiCfg.audit(r, auditEntry{Action: auditAdminReset, Failed: true, Details: "Not a dev environment"})
 		w.WriteHeader(http.StatusForbidden)
 		w.Write([]byte(`{"error": "Resetting the database is only allowed in dev environment"}`))
 		return
 	}
 	err := apiCfg.db.DeleteAllUsers(r.Context())
 	if err != nil {
-		log.Printf("Error resetting database: %s", err)
+		loggerFromContext(r.Context()).Error("Error resetting database", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte(`{"error": "Something went wrong"}`))
 		return
 	}
+	apiCfg.audit(r, auditEntry{Action: auditAdminReset})
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -121,24 +169,154 @@ func (cfg *apiConfig) handlerUpdateUsers(w http.ResponseWriter, r *http.Request)
 		respondWithError(w, http.StatusUnauthorized, "Invalid token", err)
 		return
 	}
+	before, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating user", err)
+		return
+	}
+	passwordChange := auditEntry{Action: auditPasswordChange, ActorID: userID, TargetType: auditTargetUser, TargetID: userID}
+	emailChange := auditEntry{Action: auditEmailChange, ActorID: userID, TargetType: auditTargetUser, TargetID: userID}
+	changesPassword := auth.CheckPasswordHash(before.HashedPassword, params.Password) != nil
 	u, err := cfg.db.UpdateUser(r.Context(), database.UpdateUserParams{
 		ID:             userID,
 		Email:          params.Email,
 		HashedPassword: hashedPass,
 	})
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Error updating user", err)
+		code, msg := http.StatusInternalServerError, "Error updating user"
+		if isUniqueViolation(err) {
+			code, msg = http.StatusConflict, "Email is already taken"
+		}
+		// Nothing was changed, so every change the request asked for failed.
+		if changesPassword {
+			passwordChange.Failed = true
+			passwordChange.Details = msg
+			cfg.audit(r, passwordChange)
+		}
+		if before.Email != params.Email {
+			emailChange.Failed = true
+			emailChange.Details = msg + ": " + cfg.auditEmail(before.Email) + " -> " + cfg.auditEmail(params.Email)
+			cfg.audit(r, emailChange)
+		}
+		respondWithError(w, code, msg, err)
 		return
 	}
+	if changesPassword {
+		cfg.audit(r, passwordChange)
+	}
+	if before.Email != u.Email {
+		emailChange.Details = cfg.auditEmail(before.Email) + " -> " + cfg.auditEmail(u.Email)
+		cfg.audit(r, emailChange)
+	}
 	respondWithJSON(w, http.StatusOK, User{
 		ID:          u.ID,
 		CreatedAt:   u.CreatedAt,
 		UpdatedAt:   u.UpdatedAt,
 		Email:       u.Email,
+		Handle:      u.Handle.String,
 		IsChirpyRed: u.IsChirpyRed,
 	})
 }
 
+// profileResponse counts only the chirps viewerID can list, with the same
+// visibility, block and mute rules as GET /api/chirps?author_id=.
+func (cfg *apiConfig) profileResponse(ctx context.Context, u database.User, viewerID uuid.UUID) (Profile, error) {
+	count, err := cfg.db.CountChirpsByUserID(ctx, database.CountChirpsByUserIDParams{
+		UserID:   u.ID,
+		ViewerID: viewerID,
+	})
+	if err != nil {
+		return Profile{}, err
+	}
+	return Profile{
+		ID:          u.ID,
+		CreatedAt:   u.CreatedAt,
+		Handle:      u.Handle.String,
+		DisplayName: u.DisplayName,
+		Bio:         u.Bio,
+		Location:    u.Location,
+		AvatarURL:   u.AvatarUrl,
+		IsChirpyRed: u.IsChirpyRed,
+		ChirpCount:  count,
+	}, nil
+}
+
+func (cfg *apiConfig) handlerGetProfile(w http.ResponseWriter, r *http.Request) {
+	handle := strings.TrimPrefix(r.PathValue("handle"), "@")
+	u, err := cfg.db.GetUserByHandle(r.Context(), handle)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "User not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting user", err)
+		return
+	}
+	profile, err := cfg.profileResponse(r.Context(), u, cfg.viewerID(r))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting user", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, profile)
+}
+
+func (cfg *apiConfig) handlerUpdateProfile(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Handle      string `json:"handle"`
+		DisplayName string `json:"display_name"`
+		Bio         string `json:"bio"`
+		Location    string `json:"location"`
+		AvatarURL   string `json:"avatar_url"`
+	}
+	defer r.Body.Close()
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+	if !handlePattern.MatchString(params.Handle) {
+		respondWithError(w, http.StatusBadRequest, invalidHandleMsg, nil)
+		return
+	}
+	// The limits are in characters, not bytes.
+	if utf8.RuneCountInString(params.DisplayName) > 50 || utf8.RuneCountInString(params.Bio) > 160 ||
+		utf8.RuneCountInString(params.Location) > 30 {
+		respondWithError(w, http.StatusBadRequest, "Display name, bio or location is too long", nil)
+		return
+	}
+	if params.AvatarURL != "" {
+		avatar, err := url.Parse(params.AvatarURL)
+		if err != nil || (avatar.Scheme != "https" && avatar.Scheme != "http") || avatar.Host == "" || len(params.AvatarURL) > 2048 {
+			respondWithError(w, http.StatusBadRequest, "Avatar URL must be an http(s) URL", err)
+			return
+		}
+	}
+	u, err := cfg.db.UpdateUserProfile(r.Context(), database.UpdateUserProfileParams{
+		ID:          userIDFromContext(r.Context()),
+		Handle:      sql.NullString{String: params.Handle, Valid: true},
+		DisplayName: params.DisplayName,
+		Bio:         params.Bio,
+		Location:    params.Location,
+		AvatarUrl:   params.AvatarURL,
+	})
+	if isUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "Handle is already taken", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating profile", err)
+		return
+	}
+	profile, err := cfg.profileResponse(r.Context(), u, u.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting user", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, profile)
+}
+
+const polkaUserUpgraded = "user.upgraded"
+
 func (cfg *apiConfig) handlerUpdateUserRed(w http.ResponseWriter, r *http.Request) {
 	type inner struct {
 		UserID uuid.UUID `json:"user_id"`
@@ -151,10 +329,13 @@ func (cfg *apiConfig) handlerUpdateUserRed(w http.ResponseWriter, r *http.Reques
 	//Check API key
 	polkaKey, err := auth.GetAPIKey(r.Header)
 	if err != nil {
+		cfg.metrics.webhookEvent("", webhookRejected)
 		respondWithError(w, http.StatusUnauthorized, "Invalid API key", err)
 		return
 	}
 	if polkaKey != cfg.polkaKey {
+		cfg.audit(r, auditEntry{Action: auditUpgrade, Failed: true, Details: "Invalid API key"})
+		cfg.metrics.webhookEvent("", webhookRejected)
 		respondWithError(w, http.StatusUnauthorized, "Invalid API key", nil)
 		return
 	}
@@ -162,22 +343,33 @@ func (cfg *apiConfig) handlerUpdateUserRed(w http.ResponseWriter, r *http.Reques
 	params := parameters{}
 	err = decoder.Decode(&params)
 	if err != nil {
+		cfg.metrics.webhookEvent("", webhookRejected)
 		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
 		return
 	}
-	if params.Event != "user.upgraded" {
+	if params.Event != polkaUserUpgraded {
+		cfg.metrics.webhookEvent(params.Event, webhookIgnored)
 		respondWithJSON(w, http.StatusNoContent, nil)
 		return
 	} else {
 		if params.Data.UserID == uuid.Nil {
+			cfg.metrics.webhookEvent(params.Event, webhookRejected)
 			respondWithError(w, http.StatusNotFound, "User ID is required", nil)
 			return
 		}
 		u, err := cfg.db.UpdateUserChirpyRed(r.Context(), params.Data.UserID)
 		if err != nil {
+			cfg.metrics.webhookEvent(params.Event, webhookFailed)
 			respondWithError(w, http.StatusInternalServerError, "Error updating user", err)
 			return
 		}
+		cfg.metrics.webhookEvent(params.Event, webhookProcessed)
+		cfg.audit(r, auditEntry{
+			Action:     auditUpgrade,
+			TargetType: auditTargetUser,
+			TargetID:   u.ID,
+			Details:    "Chirpy Red via Polka",
+		})
 		respondWithJSON(w, http.StatusNoContent, map[string]interface{}{
 			"id":            u.ID,
 			"is_chirpy_red": u.IsChirpyRed,
@@ -185,3 +377,37 @@ func (cfg *apiConfig) handlerUpdateUserRed(w http.ResponseWriter, r *http.Reques
 	}
 
 }
+
+type Preferences struct {
+	ExpandSensitiveContent bool `json:"expand_sensitive_content"`
+}
+
+func (cfg *apiConfig) handlerGetPreferences(w http.ResponseWriter, r *http.Request) {
+	u, err := cfg.db.GetUserByID(r.Context(), userIDFromContext(r.Context()))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting preferences", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, Preferences{
+		ExpandSensitiveContent: u.ExpandSensitiveContent,
+	})
+}
+
+func (cfg *apiConfig) handlerUpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	params := Preferences{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	u, err := cfg.db.UpdateUserPreferences(r.Context(), database.UpdateUserPreferencesParams{
+		ID:                     userIDFromContext(r.Context()),
+		ExpandSensitiveContent: params.ExpandSensitiveContent,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating preferences", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, Preferences{
+		ExpandSensitiveContent: u.ExpandSensitiveContent,
+	})
+}
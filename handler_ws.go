@@ -0,0 +1,31 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/kien-tn/chirpy/internal/auth"
+)
+
+func (cfg *apiConfig) handlerWebSocket(w http.ResponseWriter, r *http.Request) {
+	// Browsers can't set headers on the upgrade request, so fall back to a
+	// query parameter when there is no Authorization header.
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		token = r.URL.Query().Get("access_token")
+	}
+	if token == "" {
+		respondWithError(w, http.StatusUnauthorized, "Authorization header required", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.secretKey)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token", err)
+		return
+	}
+	setRequestUser(r.Context(), userID)
+	if _, ok := cfg.checkAccount(w, r, userID); !ok {
+		return
+	}
+	// ServeWS has already written the HTTP error response when it fails.
+	cfg.hub.ServeWS(w, r, userID)
+}
@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignedURL(t *testing.T) {
+	secret := "01234567890123456789012345678901"
+	expires := time.Now().Add(time.Minute)
+	sig := SignURL("/api/exports/1/download", expires, secret)
+
+	if err := ValidateSignedURL("/api/exports/1/download", expires, sig, secret); err != nil {
+		t.Fatalf("Error validating signed URL: %v", err)
+	}
+	if err := ValidateSignedURL("/api/exports/2/download", expires, sig, secret); err == nil {
+		t.Fatalf("Expected error for a different path")
+	}
+	if err := ValidateSignedURL("/api/exports/1/download", expires.Add(time.Hour), sig, secret); err == nil {
+		t.Fatalf("Expected error for a different expiry")
+	}
+	if err := ValidateSignedURL("/api/exports/1/download", expires, sig, "other secret"); err == nil {
+		t.Fatalf("Expected error for a different secret")
+	}
+
+	past := time.Now().Add(-time.Minute)
+	sig = SignURL("/api/exports/1/download", past, secret)
+	if err := ValidateSignedURL("/api/exports/1/download", past, sig, secret); err == nil {
+		t.Fatalf("Expected error for an expired link")
+	}
+}
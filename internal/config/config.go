@@ -0,0 +1,365 @@
+// Package config loads Chirpy's settings once at startup from defaults, an
+// optional YAML or TOML file, a .env file and the environment.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"github.com/kien-tn/chirpy/internal/spam"
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds every setting. The env tag names the environment variable for
+// a field, and redact marks secrets hidden by Redacted: "secret" hides the
+// whole value and "url" only the password in a URL.
+type Config struct {
+	// Platform is "dev" for local development, which allows resetting the
+	// database.
+	Platform string   `yaml:"platform" toml:"platform" env:"PLATFORM"`
+	LogLevel string   `yaml:"log_level" toml:"log_level" env:"LOG_LEVEL"`
+	Database Database `yaml:"database" toml:"database"`
+	Auth     Auth     `yaml:"auth" toml:"auth"`
+	Server   Server   `yaml:"server" toml:"server"`
+	Storage  Storage  `yaml:"storage" toml:"storage"`
+	Spam     Spam     `yaml:"spam" toml:"spam"`
+	Tracing  Tracing  `yaml:"tracing" toml:"tracing"`
+}
+
+type Database struct {
+	URL string `yaml:"url" toml:"url" env:"DB_URL" redact:"url"`
+}
+
+type Auth struct {
+	// SecretKey signs access tokens and download links.
+	SecretKey string `yaml:"secret_key" toml:"secret_key" env:"SECRET_KEY" redact:"secret"`
+	// PolkaKey is the API key Polka sends with its webhooks.
+	PolkaKey string `yaml:"polka_key" toml:"polka_key" env:"POLKA_KEY" redact:"secret"`
+}
+
+// Server holds the HTTP server settings. Zero timeouts mean none.
+type Server struct {
+	Addr              string        `yaml:"addr" toml:"addr" env:"SERVER_ADDR"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" toml:"read_timeout" env:"SERVER_READ_TIMEOUT"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" toml:"read_header_timeout" env:"SERVER_READ_HEADER_TIMEOUT"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" toml:"write_timeout" env:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" toml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT"`
+	// ShutdownTimeout bounds how long in-flight requests, websocket
+	// connections and background workers get to finish on shutdown.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT"`
+	MaxHeaderBytes  int           `yaml:"max_header_bytes" toml:"max_header_bytes" env:"SERVER_MAX_HEADER_BYTES"`
+	// MaxBodyBytes caps request bodies, except for routes that enforce
+	// their own limit.
+	MaxBodyBytes int64  `yaml:"max_body_bytes" toml:"max_body_bytes" env:"SERVER_MAX_BODY_BYTES"`
+	TLSCertFile  string `yaml:"tls_cert_file" toml:"tls_cert_file" env:"TLS_CERT_FILE"`
+	TLSKeyFile   string `yaml:"tls_key_file" toml:"tls_key_file" env:"TLS_KEY_FILE"`
+}
+
+// TLS reports whether the server should serve HTTPS.
+func (s Server) TLS() bool {
+	return s.TLSCertFile != ""
+}
+
+// Storage picks where media and data exports are kept: Backend is "local"
+// or "s3" for any S3-compatible service.
+type Storage struct {
+	Backend      string `yaml:"backend" toml:"backend" env:"BLOB_STORE"`
+	MediaDir     string `yaml:"media_dir" toml:"media_dir" env:"MEDIA_DIR"`
+	MediaBaseURL string `yaml:"media_base_url" toml:"media_base_url" env:"MEDIA_BASE_URL"`
+	ExportDir    string `yaml:"export_dir" toml:"export_dir" env:"EXPORT_DIR"`
+	S3           S3     `yaml:"s3" toml:"s3"`
+}
+
+type S3 struct {
+	Endpoint string `yaml:"endpoint" toml:"endpoint" env:"S3_ENDPOINT"`
+	Region   string `yaml:"region" toml:"region" env:"S3_REGION"`
+	Bucket   string `yaml:"bucket" toml:"bucket" env:"S3_BUCKET"`
+	// ExportBucket defaults to Bucket.
+	ExportBucket    string `yaml:"export_bucket" toml:"export_bucket" env:"S3_EXPORT_BUCKET"`
+	AccessKeyID     string `yaml:"access_key_id" toml:"access_key_id" env:"S3_ACCESS_KEY_ID"`
+	SecretAccessKey string `yaml:"secret_access_key" toml:"secret_access_key" env:"S3_SECRET_ACCESS_KEY" redact:"secret"`
+	PublicURL       string `yaml:"public_url" toml:"public_url" env:"S3_PUBLIC_URL"`
+}
+
+// Spam holds the spam filter thresholds; 0 disables an action. The other
+// filter settings keep their defaults.
+type Spam struct {
+	DelayScore  int           `yaml:"delay_score" toml:"delay_score" env:"SPAM_DELAY_SCORE"`
+	QueueScore  int           `yaml:"queue_score" toml:"queue_score" env:"SPAM_QUEUE_SCORE"`
+	RejectScore int           `yaml:"reject_score" toml:"reject_score" env:"SPAM_REJECT_SCORE"`
+	Delay       time.Duration `yaml:"delay" toml:"delay" env:"SPAM_DELAY"`
+}
+
+// Config returns the spam filter configuration with these thresholds.
+func (s Spam) Config() spam.Config {
+	cfg := spam.DefaultConfig()
+	cfg.DelayScore = s.DelayScore
+	cfg.QueueScore = s.QueueScore
+	cfg.RejectScore = s.RejectScore
+	cfg.DelayFor = s.Delay
+	return cfg
+}
+
+// Tracing picks where OpenTelemetry spans go. The OTLP exporter reads the
+// rest of its settings from the standard OTEL_EXPORTER_OTLP_* variables.
+type Tracing struct {
+	// Exporter is "none", "stdout" or "otlp".
+	Exporter string `yaml:"exporter" toml:"exporter" env:"OTEL_TRACES_EXPORTER"`
+	// OTLPProtocol is "http/protobuf" or "grpc".
+	OTLPProtocol string `yaml:"otlp_protocol" toml:"otlp_protocol" env:"OTEL_EXPORTER_OTLP_PROTOCOL"`
+}
+
+func Default() Config {
+	sc := spam.DefaultConfig()
+	return Config{
+		LogLevel: "info",
+		Server: Server{
+			Addr:              ":8080",
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   10 * time.Second,
+			MaxHeaderBytes:    1 << 20,
+			MaxBodyBytes:      1 << 20,
+		},
+		Storage: Storage{
+			Backend:      "local",
+			MediaDir:     "uploads",
+			MediaBaseURL: "/media",
+			ExportDir:    "exports",
+		},
+		Spam: Spam{
+			DelayScore:  sc.DelayScore,
+			QueueScore:  sc.QueueScore,
+			RejectScore: sc.RejectScore,
+			Delay:       sc.DelayFor,
+		},
+		Tracing: Tracing{
+			Exporter:     "none",
+			OTLPProtocol: "http/protobuf",
+		},
+	}
+}
+
+// Load builds the configuration, each source overriding the ones before it:
+//
+//  1. the defaults
+//  2. the YAML (.yaml or .yml) or TOML (.toml) file at path, unless path is
+//     empty
+//  3. the .env file in the working directory, if there is one
+//  4. the environment
+//
+// Empty environment variables are ignored. The result is validated.
+func Load(path string) (Config, error) {
+	// godotenv doesn't override variables that are already set, which
+	// puts the environment ahead of .env.
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return Config{}, fmt.Errorf("reading .env: %w", err)
+	}
+	return load(path, os.Getenv)
+}
+
+func load(path string, getenv func(string) string) (Config, error) {
+	cfg := Default()
+	if path != "" {
+		if err := readFile(path, &cfg); err != nil {
+			return Config{}, err
+		}
+	}
+	if err := applyEnv(reflect.ValueOf(&cfg).Elem(), getenv); err != nil {
+		return Config{}, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// readFile decodes the file at path over cfg. Unknown keys are an error so
+// that typos don't go unnoticed.
+func readFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+	switch ext := filepath.Ext(path); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		// An empty file decodes to io.EOF.
+		if err := dec.Decode(cfg); err != nil && len(bytes.TrimSpace(data)) > 0 {
+			return fmt.Errorf("parsing %s: %w", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("parsing %s: unknown key %s", path, undecoded[0])
+		}
+	default:
+		return fmt.Errorf("config file %s must be .yaml, .yml or .toml, not %q", path, ext)
+	}
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// applyEnv sets the fields of the struct v that have an env tag from the
+// variables getenv returns, recursing into nested structs.
+func applyEnv(v reflect.Value, getenv func(string) string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, fv := t.Field(i), v.Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			if err := applyEnv(fv, getenv); err != nil {
+				return err
+			}
+			continue
+		}
+		name := field.Tag.Get("env")
+		if name == "" {
+			continue
+		}
+		s := getenv(name)
+		if s == "" {
+			continue
+		}
+		switch {
+		case field.Type == durationType:
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", name, err)
+			}
+			fv.SetInt(int64(d))
+		case field.Type.Kind() == reflect.String:
+			fv.SetString(s)
+		case field.Type.Kind() == reflect.Int, field.Type.Kind() == reflect.Int64:
+			n, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", name, err)
+			}
+			fv.SetInt(n)
+		default:
+			return fmt.Errorf("unsupported type %s for %s", field.Type, name)
+		}
+	}
+	return nil
+}
+
+// Validate checks for missing and malformed settings, reporting every
+// problem at once. Settings are named by their environment variables.
+func (c Config) Validate() error {
+	var errs []error
+	if c.Database.URL == "" {
+		errs = append(errs, errors.New("DB_URL is required"))
+	}
+	if c.Auth.SecretKey == "" {
+		errs = append(errs, errors.New("SECRET_KEY is required"))
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		errs = append(errs, fmt.Errorf("LOG_LEVEL must be debug, info, warn or error, not %q", c.LogLevel))
+	}
+
+	s := c.Server
+	if s.Addr == "" {
+		errs = append(errs, errors.New("SERVER_ADDR is required"))
+	}
+	for name, d := range map[string]time.Duration{
+		"SERVER_READ_TIMEOUT":        s.ReadTimeout,
+		"SERVER_READ_HEADER_TIMEOUT": s.ReadHeaderTimeout,
+		"SERVER_WRITE_TIMEOUT":       s.WriteTimeout,
+		"SERVER_IDLE_TIMEOUT":        s.IdleTimeout,
+		"SERVER_SHUTDOWN_TIMEOUT":    s.ShutdownTimeout,
+	} {
+		if d < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative", name))
+		}
+	}
+	if s.MaxHeaderBytes <= 0 {
+		errs = append(errs, errors.New("SERVER_MAX_HEADER_BYTES must be positive"))
+	}
+	if s.MaxBodyBytes <= 0 {
+		errs = append(errs, errors.New("SERVER_MAX_BODY_BYTES must be positive"))
+	}
+	if (s.TLSCertFile == "") != (s.TLSKeyFile == "") {
+		errs = append(errs, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together"))
+	}
+
+	switch c.Storage.Backend {
+	case "local":
+	case "s3":
+		if c.Storage.S3.Endpoint == "" || c.Storage.S3.Bucket == "" {
+			errs = append(errs, errors.New("S3_ENDPOINT and S3_BUCKET are required for the s3 BLOB_STORE"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("BLOB_STORE must be local or s3, not %q", c.Storage.Backend))
+	}
+
+	if err := c.Spam.Config().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("invalid spam settings: %w", err))
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout", "otlp":
+	default:
+		errs = append(errs, fmt.Errorf("OTEL_TRACES_EXPORTER must be none, stdout or otlp, not %q", c.Tracing.Exporter))
+	}
+	switch c.Tracing.OTLPProtocol {
+	case "http/protobuf", "grpc":
+	default:
+		errs = append(errs, fmt.Errorf("OTEL_EXPORTER_OTLP_PROTOCOL must be http/protobuf or grpc, not %q", c.Tracing.OTLPProtocol))
+	}
+	return errors.Join(errs...)
+}
+
+const redactedValue = "REDACTED"
+
+// Redacted returns a copy of c that is safe to print, with secrets replaced.
+func (c Config) Redacted() Config {
+	redact(reflect.ValueOf(&c).Elem())
+	return c
+}
+
+func redact(v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, fv := t.Field(i), v.Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			redact(fv)
+			continue
+		}
+		if fv.Kind() != reflect.String || fv.String() == "" {
+			continue
+		}
+		switch field.Tag.Get("redact") {
+		case "secret":
+			fv.SetString(redactedValue)
+		case "url":
+			fv.SetString(redactURL(fv.String()))
+		}
+	}
+}
+
+// redactURL hides the password in a URL. Values that don't parse as URLs,
+// such as key=value connection strings, are hidden entirely.
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" {
+		return redactedValue
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), redactedValue)
+	}
+	return u.String()
+}
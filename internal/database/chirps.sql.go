@@ -7,29 +7,114 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const countChirpsByUserID = `-- name: CountChirpsByUserID :one
+SELECT COUNT(*) FROM chirps c
+JOIN users u ON u.id = c.user_id
+WHERE c.user_id = $1 AND c.deleted_at IS NULL AND u.deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT 1 FROM blocks bl WHERE bl.blocker_id = c.user_id AND bl.blocked_id = $2
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM mutes mu WHERE mu.muter_id = $2 AND mu.muted_id = c.user_id
+    )
+    AND (u.account_state <> 'shadowbanned'
+        OR u.account_state_expires_at <= (NOW() AT TIME ZONE 'UTC')
+        OR c.user_id = $2)
+    AND (c.hold = '' OR c.user_id = $2)
+`
+
+type CountChirpsByUserIDParams struct {
+	UserID   uuid.UUID
+	ViewerID uuid.UUID
+}
+
+func (q *Queries) CountChirpsByUserID(ctx context.Context, arg CountChirpsByUserIDParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countChirpsByUserID, arg.UserID, arg.ViewerID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countChirpsSince = `-- name: CountChirpsSince :one
+SELECT COUNT(*) FROM chirps WHERE user_id = $1 AND created_at > $2
+`
+
+type CountChirpsSinceParams struct {
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+func (q *Queries) CountChirpsSince(ctx context.Context, arg CountChirpsSinceParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countChirpsSince, arg.UserID, arg.CreatedAt)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countDuplicateChirps = `-- name: CountDuplicateChirps :one
+SELECT COUNT(*) FROM chirps WHERE user_id = $1 AND body_hash = $2 AND created_at > $3
+`
+
+type CountDuplicateChirpsParams struct {
+	UserID    uuid.UUID
+	BodyHash  string
+	CreatedAt time.Time
+}
+
+func (q *Queries) CountDuplicateChirps(ctx context.Context, arg CountDuplicateChirpsParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countDuplicateChirps, arg.UserID, arg.BodyHash, arg.CreatedAt)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, quoted_chirp_id, content_warning, sensitive_media, body_hash, hold, held_until)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, deleted_at, quoted_chirp_id, content_warning, sensitive_media, removed_at, body_hash, hold, held_until
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body           string
+	UserID         uuid.UUID
+	QuotedChirpID  uuid.NullUUID
+	ContentWarning string
+	SensitiveMedia bool
+	BodyHash       string
+	Hold           string
+	HeldUntil      sql.NullTime
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.QuotedChirpID,
+		arg.ContentWarning,
+		arg.SensitiveMedia,
+		arg.BodyHash,
+		arg.Hold,
+		arg.HeldUntil,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -37,6 +122,14 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.DeletedAt,
+		&i.QuotedChirpID,
+		&i.ContentWarning,
+		&i.SensitiveMedia,
+		&i.RemovedAt,
+		&i.BodyHash,
+		&i.Hold,
+		&i.HeldUntil,
 	)
 	return i, err
 }
@@ -51,7 +144,9 @@ func (q *Queries) DeleteAllChirps(ctx context.Context) error {
 }
 
 const deleteChirpByID = `-- name: DeleteChirpByID :exec
-DELETE FROM chirps WHERE id = $1 AND user_id = $2
+UPDATE chirps
+SET deleted_at = NOW()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
 `
 
 type DeleteChirpByIDParams struct {
@@ -65,11 +160,18 @@ func (q *Queries) DeleteChirpByID(ctx context.Context, arg DeleteChirpByIDParams
 }
 
 const getAllChirps = `-- name: GetAllChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps ORDER BY created_at ASC
+SELECT c.id, c.created_at, c.updated_at, c.body, c.user_id, c.deleted_at, c.quoted_chirp_id, c.content_warning, c.sensitive_media, c.removed_at, c.body_hash, c.hold, c.held_until FROM chirps c
+JOIN users u ON u.id = c.user_id
+WHERE c.deleted_at IS NULL AND u.deleted_at IS NULL
+    AND (u.account_state <> 'shadowbanned'
+        OR u.account_state_expires_at <= (NOW() AT TIME ZONE 'UTC')
+        OR c.user_id = $1)
+    AND (c.hold = '' OR c.user_id = $1)
+ORDER BY c.created_at ASC
 `
 
-func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getAllChirps)
+func (q *Queries) GetAllChirps(ctx context.Context, viewerID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getAllChirps, viewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -83,6 +185,14 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.DeletedAt,
+			&i.QuotedChirpID,
+			&i.ContentWarning,
+			&i.SensitiveMedia,
+			&i.RemovedAt,
+			&i.BodyHash,
+			&i.Hold,
+			&i.HeldUntil,
 		); err != nil {
 			return nil, err
 		}
@@ -98,7 +208,9 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const getChirpByID = `-- name: GetChirpByID :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps WHERE id = $1
+SELECT c.id, c.created_at, c.updated_at, c.body, c.user_id, c.deleted_at, c.quoted_chirp_id, c.content_warning, c.sensitive_media, c.removed_at, c.body_hash, c.hold, c.held_until FROM chirps c
+JOIN users u ON u.id = c.user_id
+WHERE c.id = $1 AND c.deleted_at IS NULL AND u.deleted_at IS NULL
 `
 
 func (q *Queries) GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error) {
@@ -110,16 +222,247 @@ func (q *Queries) GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error)
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.DeletedAt,
+		&i.QuotedChirpID,
+		&i.ContentWarning,
+		&i.SensitiveMedia,
+		&i.RemovedAt,
+		&i.BodyHash,
+		&i.Hold,
+		&i.HeldUntil,
 	)
 	return i, err
 }
 
+const getChirpsByIDs = `-- name: GetChirpsByIDs :many
+SELECT c.id, c.created_at, c.updated_at, c.body, c.user_id, c.deleted_at, c.quoted_chirp_id, c.content_warning, c.sensitive_media, c.removed_at, c.body_hash, c.hold, c.held_until FROM chirps c
+JOIN users u ON u.id = c.user_id
+WHERE c.id = ANY($1::uuid[]) AND c.deleted_at IS NULL AND u.deleted_at IS NULL
+    AND (u.account_state <> 'shadowbanned'
+        OR u.account_state_expires_at <= (NOW() AT TIME ZONE 'UTC')
+        OR c.user_id = $2)
+    AND (c.hold = '' OR c.user_id = $2)
+`
+
+type GetChirpsByIDsParams struct {
+	Ids      []uuid.UUID
+	ViewerID uuid.UUID
+}
+
+func (q *Queries) GetChirpsByIDs(ctx context.Context, arg GetChirpsByIDsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIDs, pq.Array(arg.Ids), arg.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.DeletedAt,
+			&i.QuotedChirpID,
+			&i.ContentWarning,
+			&i.SensitiveMedia,
+			&i.RemovedAt,
+			&i.BodyHash,
+			&i.Hold,
+			&i.HeldUntil,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getChirpsByUserID = `-- name: GetChirpsByUserID :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps WHERE user_id = $1 ORDER BY created_at ASC
+SELECT c.id, c.created_at, c.updated_at, c.body, c.user_id, c.deleted_at, c.quoted_chirp_id, c.content_warning, c.sensitive_media, c.removed_at, c.body_hash, c.hold, c.held_until FROM chirps c
+JOIN users u ON u.id = c.user_id
+WHERE c.user_id = $1 AND c.deleted_at IS NULL AND u.deleted_at IS NULL
+    AND (u.account_state <> 'shadowbanned'
+        OR u.account_state_expires_at <= (NOW() AT TIME ZONE 'UTC')
+        OR c.user_id = $2)
+    AND (c.hold = '' OR c.user_id = $2)
+ORDER BY c.created_at ASC
+`
+
+type GetChirpsByUserIDParams struct {
+	UserID   uuid.UUID
+	ViewerID uuid.UUID
+}
+
+func (q *Queries) GetChirpsByUserID(ctx context.Context, arg GetChirpsByUserIDParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByUserID, arg.UserID, arg.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.DeletedAt,
+			&i.QuotedChirpID,
+			&i.ContentWarning,
+			&i.SensitiveMedia,
+			&i.RemovedAt,
+			&i.BodyHash,
+			&i.Hold,
+			&i.HeldUntil,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDeletedChirpsByUserID = `-- name: GetDeletedChirpsByUserID :many
+SELECT id, created_at, updated_at, body, user_id, deleted_at, quoted_chirp_id, content_warning, sensitive_media, removed_at, body_hash, hold, held_until FROM chirps
+WHERE user_id = $1 AND removed_at IS NULL
+  AND deleted_at > NOW() - make_interval(days => $2::int)
+ORDER BY deleted_at DESC
+`
+
+type GetDeletedChirpsByUserIDParams struct {
+	UserID        uuid.UUID
+	RetentionDays int32
+}
+
+func (q *Queries) GetDeletedChirpsByUserID(ctx context.Context, arg GetDeletedChirpsByUserIDParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getDeletedChirpsByUserID, arg.UserID, arg.RetentionDays)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.DeletedAt,
+			&i.QuotedChirpID,
+			&i.ContentWarning,
+			&i.SensitiveMedia,
+			&i.RemovedAt,
+			&i.BodyHash,
+			&i.Hold,
+			&i.HeldUntil,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getVisibleChirpByID = `-- name: GetVisibleChirpByID :one
+SELECT c.id, c.created_at, c.updated_at, c.body, c.user_id, c.deleted_at, c.quoted_chirp_id, c.content_warning, c.sensitive_media, c.removed_at, c.body_hash, c.hold, c.held_until FROM chirps c
+JOIN users u ON u.id = c.user_id
+WHERE c.id = $1 AND c.deleted_at IS NULL AND u.deleted_at IS NULL
+    AND (u.account_state <> 'shadowbanned'
+        OR u.account_state_expires_at <= (NOW() AT TIME ZONE 'UTC')
+        OR c.user_id = $2)
+    AND (c.hold = '' OR c.user_id = $2)
+`
+
+type GetVisibleChirpByIDParams struct {
+	ID       uuid.UUID
+	ViewerID uuid.UUID
+}
+
+func (q *Queries) GetVisibleChirpByID(ctx context.Context, arg GetVisibleChirpByIDParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getVisibleChirpByID, arg.ID, arg.ViewerID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.DeletedAt,
+		&i.QuotedChirpID,
+		&i.ContentWarning,
+		&i.SensitiveMedia,
+		&i.RemovedAt,
+		&i.BodyHash,
+		&i.Hold,
+		&i.HeldUntil,
+	)
+	return i, err
+}
+
+const listChirps = `-- name: ListChirps :many
+SELECT c.id, c.created_at, c.updated_at, c.body, c.user_id, c.deleted_at, c.quoted_chirp_id, c.content_warning, c.sensitive_media, c.removed_at, c.body_hash, c.hold, c.held_until FROM chirps c
+JOIN users u ON u.id = c.user_id
+WHERE c.deleted_at IS NULL AND u.deleted_at IS NULL
+    AND ($1::uuid = '00000000-0000-0000-0000-000000000000' OR c.user_id = $1)
+    AND NOT EXISTS (
+        SELECT 1 FROM blocks bl WHERE bl.blocker_id = c.user_id AND bl.blocked_id = $2
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM mutes mu WHERE mu.muter_id = $2 AND mu.muted_id = c.user_id
+    )
+    AND (u.account_state <> 'shadowbanned'
+        OR u.account_state_expires_at <= (NOW() AT TIME ZONE 'UTC')
+        OR c.user_id = $2)
+    AND (c.hold = '' OR c.user_id = $2)
+    AND ($1::uuid = '00000000-0000-0000-0000-000000000000' OR NOT EXISTS (
+        SELECT 1 FROM pinned_chirps p WHERE p.user_id = c.user_id AND p.chirp_id = c.id
+    ))
+    AND (c.created_at, c.id) < ($3::timestamp, $4::uuid)
+ORDER BY c.created_at DESC, c.id DESC
+LIMIT $5
 `
 
-func (q *Queries) GetChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirpsByUserID, userID)
+type ListChirpsParams struct {
+	AuthorID   uuid.UUID
+	ViewerID   uuid.UUID
+	CursorTime time.Time
+	CursorID   uuid.UUID
+	PageSize   int32
+}
+
+func (q *Queries) ListChirps(ctx context.Context, arg ListChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirps,
+		arg.AuthorID,
+		arg.ViewerID,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -133,6 +476,14 @@ func (q *Queries) GetChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Ch
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.DeletedAt,
+			&i.QuotedChirpID,
+			&i.ContentWarning,
+			&i.SensitiveMedia,
+			&i.RemovedAt,
+			&i.BodyHash,
+			&i.Hold,
+			&i.HeldUntil,
 		); err != nil {
 			return nil, err
 		}
@@ -146,3 +497,248 @@ func (q *Queries) GetChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Ch
 	}
 	return items, nil
 }
+
+const listPinnedChirps = `-- name: ListPinnedChirps :many
+SELECT c.id, c.created_at, c.updated_at, c.body, c.user_id, c.deleted_at, c.quoted_chirp_id, c.content_warning, c.sensitive_media, c.removed_at, c.body_hash, c.hold, c.held_until FROM chirps c
+JOIN pinned_chirps p ON p.user_id = c.user_id AND p.chirp_id = c.id
+JOIN users u ON u.id = c.user_id
+WHERE c.user_id = $1 AND c.deleted_at IS NULL AND u.deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT 1 FROM blocks bl WHERE bl.blocker_id = c.user_id AND bl.blocked_id = $2
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM mutes mu WHERE mu.muter_id = $2 AND mu.muted_id = c.user_id
+    )
+    AND (u.account_state <> 'shadowbanned'
+        OR u.account_state_expires_at <= (NOW() AT TIME ZONE 'UTC')
+        OR c.user_id = $2)
+    AND (c.hold = '' OR c.user_id = $2)
+ORDER BY p.position ASC
+`
+
+type ListPinnedChirpsParams struct {
+	AuthorID uuid.UUID
+	ViewerID uuid.UUID
+}
+
+func (q *Queries) ListPinnedChirps(ctx context.Context, arg ListPinnedChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listPinnedChirps, arg.AuthorID, arg.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.DeletedAt,
+			&i.QuotedChirpID,
+			&i.ContentWarning,
+			&i.SensitiveMedia,
+			&i.RemovedAt,
+			&i.BodyHash,
+			&i.Hold,
+			&i.HeldUntil,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockDueHeldChirp = `-- name: LockDueHeldChirp :one
+SELECT id, created_at, updated_at, body, user_id, deleted_at, quoted_chirp_id, content_warning, sensitive_media, removed_at, body_hash, hold, held_until FROM chirps
+WHERE hold = 'delay' AND held_until <= (NOW() AT TIME ZONE 'UTC') AND deleted_at IS NULL
+  AND user_id NOT IN (SELECT id FROM users WHERE deleted_at IS NOT NULL)
+ORDER BY held_until ASC
+LIMIT 1
+FOR UPDATE SKIP LOCKED
+`
+
+func (q *Queries) LockDueHeldChirp(ctx context.Context) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, lockDueHeldChirp)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.DeletedAt,
+		&i.QuotedChirpID,
+		&i.ContentWarning,
+		&i.SensitiveMedia,
+		&i.RemovedAt,
+		&i.BodyHash,
+		&i.Hold,
+		&i.HeldUntil,
+	)
+	return i, err
+}
+
+const purgeDeletedChirps = `-- name: PurgeDeletedChirps :many
+WITH purged AS (
+    DELETE FROM chirps
+    WHERE deleted_at <= NOW() - make_interval(days => $1::int)
+    RETURNING id
+)
+SELECT purged.id, m.storage_key, m.thumbnail_key
+FROM purged
+LEFT JOIN media_attachments m ON m.chirp_id = purged.id
+`
+
+type PurgeDeletedChirpsRow struct {
+	ID           uuid.UUID
+	StorageKey   sql.NullString
+	ThumbnailKey sql.NullString
+}
+
+func (q *Queries) PurgeDeletedChirps(ctx context.Context, retentionDays int32) ([]PurgeDeletedChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, purgeDeletedChirps, retentionDays)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PurgeDeletedChirpsRow
+	for rows.Next() {
+		var i PurgeDeletedChirpsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.StorageKey,
+			&i.ThumbnailKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const releaseChirp = `-- name: ReleaseChirp :one
+UPDATE chirps
+SET hold = '', held_until = NULL, created_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND hold <> '' AND deleted_at IS NULL
+RETURNING id, created_at, updated_at, body, user_id, deleted_at, quoted_chirp_id, content_warning, sensitive_media, removed_at, body_hash, hold, held_until
+`
+
+func (q *Queries) ReleaseChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, releaseChirp, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.DeletedAt,
+		&i.QuotedChirpID,
+		&i.ContentWarning,
+		&i.SensitiveMedia,
+		&i.RemovedAt,
+		&i.BodyHash,
+		&i.Hold,
+		&i.HeldUntil,
+	)
+	return i, err
+}
+
+const removeChirp = `-- name: RemoveChirp :execrows
+UPDATE chirps
+SET deleted_at = COALESCE(deleted_at, NOW()), removed_at = NOW()
+WHERE id = $1 AND removed_at IS NULL
+`
+
+func (q *Queries) RemoveChirp(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, removeChirp, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreChirp = `-- name: RestoreChirp :one
+UPDATE chirps
+SET deleted_at = NULL
+WHERE id = $1 AND user_id = $2 AND removed_at IS NULL
+  AND deleted_at > NOW() - make_interval(days => $3::int)
+RETURNING id, created_at, updated_at, body, user_id, deleted_at, quoted_chirp_id, content_warning, sensitive_media, removed_at, body_hash, hold, held_until
+`
+
+type RestoreChirpParams struct {
+	ID            uuid.UUID
+	UserID        uuid.UUID
+	RetentionDays int32
+}
+
+func (q *Queries) RestoreChirp(ctx context.Context, arg RestoreChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, restoreChirp, arg.ID, arg.UserID, arg.RetentionDays)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.DeletedAt,
+		&i.QuotedChirpID,
+		&i.ContentWarning,
+		&i.SensitiveMedia,
+		&i.RemovedAt,
+		&i.BodyHash,
+		&i.Hold,
+		&i.HeldUntil,
+	)
+	return i, err
+}
+
+const setChirpLabels = `-- name: SetChirpLabels :one
+UPDATE chirps
+SET content_warning = $2, sensitive_media = $3, updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING id, created_at, updated_at, body, user_id, deleted_at, quoted_chirp_id, content_warning, sensitive_media, removed_at, body_hash, hold, held_until
+`
+
+type SetChirpLabelsParams struct {
+	ID             uuid.UUID
+	ContentWarning string
+	SensitiveMedia bool
+}
+
+func (q *Queries) SetChirpLabels(ctx context.Context, arg SetChirpLabelsParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, setChirpLabels, arg.ID, arg.ContentWarning, arg.SensitiveMedia)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.DeletedAt,
+		&i.QuotedChirpID,
+		&i.ContentWarning,
+		&i.SensitiveMedia,
+		&i.RemovedAt,
+		&i.BodyHash,
+		&i.Hold,
+		&i.HeldUntil,
+	)
+	return i, err
+}
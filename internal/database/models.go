@@ -11,12 +11,213 @@ import (
 	"github.com/google/uuid"
 )
 
+type AuditLog struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	Action     string
+	Outcome    string
+	ActorID    uuid.NullUUID
+	TargetType string
+	TargetID   uuid.NullUUID
+	Ip         string
+	UserAgent  string
+	RequestID  string
+	Details    string
+}
+
+type Block struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+	CreatedAt time.Time
+}
+
+type Bookmark struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
 type Chirp struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Body           string
+	UserID         uuid.UUID
+	DeletedAt      sql.NullTime
+	QuotedChirpID  uuid.NullUUID
+	ContentWarning string
+	SensitiveMedia bool
+	RemovedAt      sql.NullTime
+	BodyHash       string
+	Hold           string
+	HeldUntil      sql.NullTime
+}
+
+type ChirpDraft struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	UserID         uuid.UUID
+	Body           string
+	MediaIds       []uuid.UUID
+	PublishAt      sql.NullTime
+	PollOptions    []string
+	PollClosesAt   sql.NullTime
+	QuotedChirpID  uuid.NullUUID
+	ContentWarning string
+	SensitiveMedia bool
+}
+
+type ChirpLink struct {
+	ChirpID  uuid.UUID
+	Url      string
+	Position int32
+}
+
+type Conversation struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
-	Body      string
+	CreatedBy uuid.UUID
+	IsGroup   bool
+	DirectKey sql.NullString
+}
+
+type ConversationParticipant struct {
+	ConversationID uuid.UUID
+	UserID         uuid.UUID
+	JoinedAt       time.Time
+	LastReadAt     sql.NullTime
+}
+
+type DataExport struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	Status     string
+	StorageKey string
+	SizeBytes  int64
+	ExpiresAt  sql.NullTime
+}
+
+type LinkPreview struct {
+	Url         string
+	FetchedAt   time.Time
+	Ok          bool
+	Title       string
+	Description string
+	ImageUrl    string
+	SiteName    string
+}
+
+type List struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	Name        string
+	Description string
+}
+
+type ListMember struct {
+	ListID    uuid.UUID
 	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type MediaAttachment struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UserID       uuid.UUID
+	ContentType  string
+	StorageKey   string
+	ThumbnailKey string
+	Width        int32
+	Height       int32
+	SizeBytes    int64
+	ChirpID      uuid.NullUUID
+	Position     int32
+}
+
+type Message struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	ConversationID uuid.UUID
+	SenderID       uuid.UUID
+	Body           string
+}
+
+type ModerationAction struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	ReportID    uuid.UUID
+	ModeratorID uuid.UUID
+	Action      string
+	FromStatus  string
+	ToStatus    string
+	AssigneeID  uuid.NullUUID
+	Note        string
+}
+
+type Mute struct {
+	MuterID   uuid.UUID
+	MutedID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type Notification struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	Type        string
+	ChirpID     uuid.NullUUID
+	GroupKey    string
+	LastActorID uuid.NullUUID
+	ReadAt      sql.NullTime
+}
+
+type NotificationActor struct {
+	NotificationID uuid.UUID
+	ActorID        uuid.UUID
+	CreatedAt      time.Time
+}
+
+type NotificationPreference struct {
+	UserID    uuid.UUID
+	Type      string
+	Enabled   bool
+	UpdatedAt time.Time
+}
+
+type PinnedChirp struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	Position  int32
+	CreatedAt time.Time
+}
+
+type Poll struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	ChirpID   uuid.UUID
+	ClosesAt  time.Time
+	EndedAt   sql.NullTime
+}
+
+type PollOption struct {
+	ID       uuid.UUID
+	PollID   uuid.UUID
+	Position int32
+	Label    string
+}
+
+type PollVote struct {
+	PollID    uuid.UUID
+	UserID    uuid.UUID
+	OptionID  uuid.UUID
+	CreatedAt time.Time
 }
 
 type RefreshToken struct {
@@ -28,11 +229,40 @@ type RefreshToken struct {
 	RevokedAt sql.NullTime
 }
 
+type Report struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	ReporterID uuid.NullUUID
+	TargetType string
+	ChirpID    uuid.NullUUID
+	UserID     uuid.NullUUID
+	ChirpBody  string
+	Reason     string
+	Details    string
+	Status     string
+	AssigneeID uuid.NullUUID
+	Resolution sql.NullString
+	ResolvedAt sql.NullTime
+	Source     string
+}
+
 type User struct {
-	ID             uuid.UUID
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	Email          string
-	HashedPassword string
-	IsChirpyRed    bool
+	ID                     uuid.UUID
+	CreatedAt              time.Time
+	UpdatedAt              time.Time
+	Email                  string
+	HashedPassword         string
+	IsChirpyRed            bool
+	Handle                 sql.NullString
+	DisplayName            string
+	Bio                    string
+	Location               string
+	AvatarUrl              string
+	DeletedAt              sql.NullTime
+	Role                   string
+	ExpandSensitiveContent bool
+	AccountState           string
+	AccountStateExpiresAt  sql.NullTime
+	AccountStateReason     string
 }
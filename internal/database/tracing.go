@@ -0,0 +1,100 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NewTraced returns Queries that record a span for every query they run,
+// named after the sqlc query.
+func NewTraced(db DBTX, tracer trace.Tracer) *Queries {
+	return New(&tracedDB{db: db, tracer: tracer})
+}
+
+// WithTracedTx is WithTx for Queries made by NewTraced: queries run in the
+// transaction are traced as well. For other Queries it is the same as WithTx.
+func (q *Queries) WithTracedTx(tx *sql.Tx) *Queries {
+	if t, ok := q.db.(*tracedDB); ok {
+		return New(&tracedDB{db: tx, tracer: t.tracer})
+	}
+	return q.WithTx(tx)
+}
+
+// tracedDB wraps a DBTX in spans. Spans end when the call returns, so for
+// QueryContext they don't cover reading the rows.
+type tracedDB struct {
+	db     DBTX
+	tracer trace.Tracer
+}
+
+func (t *tracedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := t.start(ctx, query)
+	defer span.End()
+	res, err := t.db.ExecContext(ctx, query, args...)
+	recordError(span, err)
+	return res, err
+}
+
+func (t *tracedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	ctx, span := t.start(ctx, query)
+	defer span.End()
+	stmt, err := t.db.PrepareContext(ctx, query)
+	recordError(span, err)
+	return stmt, err
+}
+
+func (t *tracedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := t.start(ctx, query)
+	defer span.End()
+	rows, err := t.db.QueryContext(ctx, query, args...)
+	recordError(span, err)
+	return rows, err
+}
+
+func (t *tracedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := t.start(ctx, query)
+	defer span.End()
+	row := t.db.QueryRowContext(ctx, query, args...)
+	// sql.ErrNoRows only shows up in Scan, so it isn't recorded as an error.
+	recordError(span, row.Err())
+	return row
+}
+
+func (t *tracedDB) start(ctx context.Context, query string) (context.Context, trace.Span) {
+	name := queryName(query)
+	return t.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(name),
+			semconv.DBQueryText(query),
+		),
+	)
+}
+
+// queryName returns the name from the "-- name: GetUser :one" comment sqlc
+// puts at the start of each query, or "query" for SQL without one.
+func queryName(query string) string {
+	const prefix = "-- name: "
+	if !strings.HasPrefix(query, prefix) {
+		return "query"
+	}
+	fields := strings.Fields(query[len(prefix):])
+	if len(fields) == 0 {
+		return "query"
+	}
+	return fields[0]
+}
+
+func recordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,79 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// fakeDB fails every call with err.
+type fakeDB struct {
+	err error
+}
+
+func (f fakeDB) ExecContext(context.Context, string, ...interface{}) (sql.Result, error) {
+	return nil, f.err
+}
+
+func (f fakeDB) PrepareContext(context.Context, string) (*sql.Stmt, error) {
+	return nil, f.err
+}
+
+func (f fakeDB) QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error) {
+	return nil, f.err
+}
+
+func (f fakeDB) QueryRowContext(context.Context, string, ...interface{}) *sql.Row {
+	return &sql.Row{}
+}
+
+func TestQueryName(t *testing.T) {
+	tests := map[string]string{
+		"-- name: GetUserByID :one\nSELECT 1": "GetUserByID",
+		"-- name: ":                           "query",
+		"SELECT 1":                            "query",
+	}
+	for query, want := range tests {
+		if got := queryName(query); got != want {
+			t.Errorf("queryName(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
+
+func TestTracedDBRecordsSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	failure := errors.New("connection refused")
+	q := NewTraced(fakeDB{err: failure}, tp.Tracer("test"))
+
+	if err := q.DeleteAllUsers(context.Background()); !errors.Is(err, failure) {
+		t.Fatalf("DeleteAllUsers returned %v, want %v", err, failure)
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	if spans[0].Name != "DeleteAllUsers" {
+		t.Errorf("span name = %q, want DeleteAllUsers", spans[0].Name)
+	}
+	if spans[0].Status.Code != codes.Error {
+		t.Errorf("span status = %v, want Error", spans[0].Status.Code)
+	}
+}
+
+func TestWithTracedTxKeepsTracing(t *testing.T) {
+	tp := sdktrace.NewTracerProvider()
+	traced := NewTraced(fakeDB{}, tp.Tracer("test")).WithTracedTx(&sql.Tx{})
+	if _, ok := traced.db.(*tracedDB); !ok {
+		t.Errorf("WithTracedTx on traced queries returned untraced queries")
+	}
+	plain := New(fakeDB{}).WithTracedTx(&sql.Tx{})
+	if _, ok := plain.db.(*tracedDB); ok {
+		t.Errorf("WithTracedTx on untraced queries returned traced queries")
+	}
+}
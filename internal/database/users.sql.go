@@ -7,31 +7,40 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password, is_chirpy_red)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
     $2,
-    $3
+    $3,
+    $4
 )
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, display_name, bio, location, avatar_url, deleted_at, role, expand_sensitive_content, account_state, account_state_expires_at, account_state_reason
 `
 
 type CreateUserParams struct {
 	Email          string
 	HashedPassword string
 	IsChirpyRed    bool
+	Handle         sql.NullString
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword, arg.IsChirpyRed)
+	row := q.db.QueryRowContext(ctx, createUser,
+		arg.Email,
+		arg.HashedPassword,
+		arg.IsChirpyRed,
+		arg.Handle,
+	)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -40,6 +49,17 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.AvatarUrl,
+		&i.DeletedAt,
+		&i.Role,
+		&i.ExpandSensitiveContent,
+		&i.AccountState,
+		&i.AccountStateExpiresAt,
+		&i.AccountStateReason,
 	)
 	return i, err
 }
@@ -53,8 +73,44 @@ func (q *Queries) DeleteAllUsers(ctx context.Context) error {
 	return err
 }
 
+const getDeletedUserByEmail = `-- name: GetDeletedUserByEmail :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, display_name, bio, location, avatar_url, deleted_at, role, expand_sensitive_content, account_state, account_state_expires_at, account_state_reason FROM users
+WHERE email = $1
+  AND deleted_at > NOW() - make_interval(days => $2::int)
+`
+
+type GetDeletedUserByEmailParams struct {
+	Email         string
+	RetentionDays int32
+}
+
+func (q *Queries) GetDeletedUserByEmail(ctx context.Context, arg GetDeletedUserByEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, getDeletedUserByEmail, arg.Email, arg.RetentionDays)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.AvatarUrl,
+		&i.DeletedAt,
+		&i.Role,
+		&i.ExpandSensitiveContent,
+		&i.AccountState,
+		&i.AccountStateExpiresAt,
+		&i.AccountStateReason,
+	)
+	return i, err
+}
+
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red FROM users WHERE email = $1
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, display_name, bio, location, avatar_url, deleted_at, role, expand_sensitive_content, account_state, account_state_expires_at, account_state_reason FROM users WHERE email = $1 AND deleted_at IS NULL
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -67,17 +123,297 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.AvatarUrl,
+		&i.DeletedAt,
+		&i.Role,
+		&i.ExpandSensitiveContent,
+		&i.AccountState,
+		&i.AccountStateExpiresAt,
+		&i.AccountStateReason,
 	)
 	return i, err
 }
 
+const getUserByHandle = `-- name: GetUserByHandle :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, display_name, bio, location, avatar_url, deleted_at, role, expand_sensitive_content, account_state, account_state_expires_at, account_state_reason FROM users WHERE LOWER(handle) = LOWER($1) AND deleted_at IS NULL
+`
+
+func (q *Queries) GetUserByHandle(ctx context.Context, handle string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByHandle, handle)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.AvatarUrl,
+		&i.DeletedAt,
+		&i.Role,
+		&i.ExpandSensitiveContent,
+		&i.AccountState,
+		&i.AccountStateExpiresAt,
+		&i.AccountStateReason,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, display_name, bio, location, avatar_url, deleted_at, role, expand_sensitive_content, account_state, account_state_expires_at, account_state_reason FROM users WHERE id = $1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.AvatarUrl,
+		&i.DeletedAt,
+		&i.Role,
+		&i.ExpandSensitiveContent,
+		&i.AccountState,
+		&i.AccountStateExpiresAt,
+		&i.AccountStateReason,
+	)
+	return i, err
+}
+
+const getUserIDsByHandles = `-- name: GetUserIDsByHandles :many
+SELECT id FROM users WHERE LOWER(handle) = ANY($1::text[]) AND deleted_at IS NULL
+`
+
+func (q *Queries) GetUserIDsByHandles(ctx context.Context, handles []string) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, getUserIDsByHandles, pq.Array(handles))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUsersByIDs = `-- name: GetUsersByIDs :many
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, display_name, bio, location, avatar_url, deleted_at, role, expand_sensitive_content, account_state, account_state_expires_at, account_state_reason FROM users WHERE id = ANY($1::uuid[])
+`
+
+func (q *Queries) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.HashedPassword,
+			&i.IsChirpyRed,
+			&i.Handle,
+			&i.DisplayName,
+			&i.Bio,
+			&i.Location,
+			&i.AvatarUrl,
+			&i.DeletedAt,
+			&i.Role,
+			&i.ExpandSensitiveContent,
+			&i.AccountState,
+			&i.AccountStateExpiresAt,
+			&i.AccountStateReason,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const purgeDeletedUsers = `-- name: PurgeDeletedUsers :many
+WITH purged AS (
+    DELETE FROM users
+    WHERE deleted_at <= NOW() - make_interval(days => $1::int)
+    RETURNING id
+)
+SELECT purged.id, m.storage_key, m.thumbnail_key
+FROM purged
+LEFT JOIN media_attachments m ON m.user_id = purged.id
+`
+
+type PurgeDeletedUsersRow struct {
+	ID           uuid.UUID
+	StorageKey   sql.NullString
+	ThumbnailKey sql.NullString
+}
+
+func (q *Queries) PurgeDeletedUsers(ctx context.Context, retentionDays int32) ([]PurgeDeletedUsersRow, error) {
+	rows, err := q.db.QueryContext(ctx, purgeDeletedUsers, retentionDays)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PurgeDeletedUsersRow
+	for rows.Next() {
+		var i PurgeDeletedUsersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.StorageKey,
+			&i.ThumbnailKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const restoreUser = `-- name: RestoreUser :one
+UPDATE users
+SET deleted_at = NULL,
+    updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NOT NULL
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, display_name, bio, location, avatar_url, deleted_at, role, expand_sensitive_content, account_state, account_state_expires_at, account_state_reason
+`
+
+func (q *Queries) RestoreUser(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, restoreUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.AvatarUrl,
+		&i.DeletedAt,
+		&i.Role,
+		&i.ExpandSensitiveContent,
+		&i.AccountState,
+		&i.AccountStateExpiresAt,
+		&i.AccountStateReason,
+	)
+	return i, err
+}
+
+const setAccountState = `-- name: SetAccountState :one
+UPDATE users
+SET account_state = $2,
+    account_state_expires_at = $3,
+    account_state_reason = $4,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, display_name, bio, location, avatar_url, deleted_at, role, expand_sensitive_content, account_state, account_state_expires_at, account_state_reason
+`
+
+type SetAccountStateParams struct {
+	ID                    uuid.UUID
+	AccountState          string
+	AccountStateExpiresAt sql.NullTime
+	AccountStateReason    string
+}
+
+func (q *Queries) SetAccountState(ctx context.Context, arg SetAccountStateParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setAccountState,
+		arg.ID,
+		arg.AccountState,
+		arg.AccountStateExpiresAt,
+		arg.AccountStateReason,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.AvatarUrl,
+		&i.DeletedAt,
+		&i.Role,
+		&i.ExpandSensitiveContent,
+		&i.AccountState,
+		&i.AccountStateExpiresAt,
+		&i.AccountStateReason,
+	)
+	return i, err
+}
+
+const softDeleteUser = `-- name: SoftDeleteUser :execrows
+UPDATE users
+SET deleted_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) SoftDeleteUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, softDeleteUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const updateUser = `-- name: UpdateUser :one
 UPDATE users
 SET updated_at = NOW(),
     email = $2,
     hashed_password = $3
 WHERE id = $1
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, display_name, bio, location, avatar_url, deleted_at, role, expand_sensitive_content, account_state, account_state_expires_at, account_state_reason
 `
 
 type UpdateUserParams struct {
@@ -96,6 +432,17 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.AvatarUrl,
+		&i.DeletedAt,
+		&i.Role,
+		&i.ExpandSensitiveContent,
+		&i.AccountState,
+		&i.AccountStateExpiresAt,
+		&i.AccountStateReason,
 	)
 	return i, err
 }
@@ -105,7 +452,7 @@ UPDATE users
 SET updated_at = NOW(),
     is_chirpy_red = true
 WHERE id = $1
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, display_name, bio, location, avatar_url, deleted_at, role, expand_sensitive_content, account_state, account_state_expires_at, account_state_reason
 `
 
 func (q *Queries) UpdateUserChirpyRed(ctx context.Context, id uuid.UUID) (User, error) {
@@ -118,6 +465,107 @@ func (q *Queries) UpdateUserChirpyRed(ctx context.Context, id uuid.UUID) (User,
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.AvatarUrl,
+		&i.DeletedAt,
+		&i.Role,
+		&i.ExpandSensitiveContent,
+		&i.AccountState,
+		&i.AccountStateExpiresAt,
+		&i.AccountStateReason,
+	)
+	return i, err
+}
+
+const updateUserPreferences = `-- name: UpdateUserPreferences :one
+UPDATE users
+SET expand_sensitive_content = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, display_name, bio, location, avatar_url, deleted_at, role, expand_sensitive_content, account_state, account_state_expires_at, account_state_reason
+`
+
+type UpdateUserPreferencesParams struct {
+	ID                     uuid.UUID
+	ExpandSensitiveContent bool
+}
+
+func (q *Queries) UpdateUserPreferences(ctx context.Context, arg UpdateUserPreferencesParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserPreferences, arg.ID, arg.ExpandSensitiveContent)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.AvatarUrl,
+		&i.DeletedAt,
+		&i.Role,
+		&i.ExpandSensitiveContent,
+		&i.AccountState,
+		&i.AccountStateExpiresAt,
+		&i.AccountStateReason,
+	)
+	return i, err
+}
+
+const updateUserProfile = `-- name: UpdateUserProfile :one
+UPDATE users
+SET updated_at = NOW(),
+    handle = $2,
+    display_name = $3,
+    bio = $4,
+    location = $5,
+    avatar_url = $6
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, display_name, bio, location, avatar_url, deleted_at, role, expand_sensitive_content, account_state, account_state_expires_at, account_state_reason
+`
+
+type UpdateUserProfileParams struct {
+	ID          uuid.UUID
+	Handle      sql.NullString
+	DisplayName string
+	Bio         string
+	Location    string
+	AvatarUrl   string
+}
+
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserProfile,
+		arg.ID,
+		arg.Handle,
+		arg.DisplayName,
+		arg.Bio,
+		arg.Location,
+		arg.AvatarUrl,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Location,
+		&i.AvatarUrl,
+		&i.DeletedAt,
+		&i.Role,
+		&i.ExpandSensitiveContent,
+		&i.AccountState,
+		&i.AccountStateExpiresAt,
+		&i.AccountStateReason,
 	)
 	return i, err
 }
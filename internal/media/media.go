@@ -0,0 +1,304 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+)
+
+const (
+	// ThumbnailSize bounds the longest side of generated thumbnails.
+	ThumbnailSize = 400
+	// MaxPixels guards against decompression bombs: small files that
+	// declare enormous dimensions.
+	MaxPixels = 40_000_000
+	// MaxFrames bounds the number of frames in an animated GIF.
+	MaxFrames = 1000
+)
+
+var ErrUnsupportedType = errors.New("unsupported media type")
+
+type Image struct {
+	ContentType          string
+	Data                 []byte
+	Width                int
+	Height               int
+	Thumbnail            []byte
+	ThumbnailContentType string
+}
+
+// Extension returns the file extension used to store a content type.
+func Extension(contentType string) string {
+	switch contentType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	}
+	return ""
+}
+
+// Process sniffs the uploaded bytes, ignoring whatever type the client
+// claimed, and re-encodes the image. Re-encoding drops all metadata, including
+// EXIF location data; the EXIF orientation is applied to the pixels first so
+// photos don't end up sideways.
+func Process(data []byte) (Image, error) {
+	contentType := http.DetectContentType(data)
+	if Extension(contentType) == "" {
+		return Image{}, fmt.Errorf("%w: %s", ErrUnsupportedType, contentType)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, err
+	}
+	if cfg.Width*cfg.Height > MaxPixels {
+		return Image{}, fmt.Errorf("image is too large: %dx%d", cfg.Width, cfg.Height)
+	}
+
+	out := Image{ContentType: contentType}
+	var buf bytes.Buffer
+	var first image.Image
+	switch contentType {
+	case "image/gif":
+		// DecodeAll allocates every frame, so a GIF with a modest logical
+		// screen can still hold many full-size frames.
+		frames, pixels, err := gifFrames(data)
+		if err != nil {
+			return Image{}, err
+		}
+		if frames > MaxFrames || pixels > MaxPixels {
+			return Image{}, fmt.Errorf("animation is too large: %d frames, %d pixels", frames, pixels)
+		}
+		g, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return Image{}, err
+		}
+		if err := gif.EncodeAll(&buf, g); err != nil {
+			return Image{}, err
+		}
+		first = g.Image[0]
+	case "image/jpeg":
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return Image{}, err
+		}
+		first = orient(img, jpegOrientation(data))
+		if err := jpeg.Encode(&buf, first, &jpeg.Options{Quality: 90}); err != nil {
+			return Image{}, err
+		}
+	case "image/png":
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return Image{}, err
+		}
+		first = img
+		if err := png.Encode(&buf, img); err != nil {
+			return Image{}, err
+		}
+	}
+	out.Data = buf.Bytes()
+	out.Width = first.Bounds().Dx()
+	out.Height = first.Bounds().Dy()
+
+	thumb := thumbnail(first, ThumbnailSize)
+	buf = bytes.Buffer{}
+	if contentType == "image/jpeg" {
+		out.ThumbnailContentType = "image/jpeg"
+		err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 80})
+	} else {
+		out.ThumbnailContentType = "image/png"
+		err = png.Encode(&buf, thumb)
+	}
+	if err != nil {
+		return Image{}, err
+	}
+	out.Thumbnail = buf.Bytes()
+	return out, nil
+}
+
+// gifFrames walks a GIF's blocks without decoding any image data and returns
+// the number of frames and their combined pixel count.
+func gifFrames(data []byte) (frames, pixels int, err error) {
+	errFormat := errors.New("gif: malformed block structure")
+	if len(data) < 13 {
+		return 0, 0, errFormat
+	}
+	pos := 13
+	if data[10]&0x80 != 0 {
+		pos += 3 << (data[10]&0x07 + 1)
+	}
+	// skipSubBlocks advances past a sequence of data sub-blocks ending with
+	// an empty one.
+	skipSubBlocks := func() bool {
+		for pos < len(data) {
+			n := int(data[pos])
+			pos += 1 + n
+			if n == 0 {
+				return true
+			}
+		}
+		return false
+	}
+	for pos < len(data) {
+		switch data[pos] {
+		case 0x21: // extension
+			pos += 2
+			if !skipSubBlocks() {
+				return 0, 0, errFormat
+			}
+		case 0x2C: // image descriptor
+			if pos+10 > len(data) {
+				return 0, 0, errFormat
+			}
+			w := int(binary.LittleEndian.Uint16(data[pos+5:]))
+			h := int(binary.LittleEndian.Uint16(data[pos+7:]))
+			flags := data[pos+9]
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << (flags&0x07 + 1)
+			}
+			pos++ // LZW minimum code size
+			if !skipSubBlocks() {
+				return 0, 0, errFormat
+			}
+			frames++
+			pixels += w * h
+		case 0x3B: // trailer
+			return frames, pixels, nil
+		default:
+			return 0, 0, errFormat
+		}
+	}
+	// The decoder tolerates a missing trailer.
+	return frames, pixels, nil
+}
+
+// thumbnail scales img to fit within size x size, keeping its aspect ratio.
+func thumbnail(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= size && h <= size {
+		return img
+	}
+	if w >= h {
+		h = max(1, h*size/w)
+		w = size
+	} else {
+		w = max(1, w*size/h)
+		h = size
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+// jpegOrientation reads the EXIF orientation tag (1-8) from a JPEG, returning
+// 1 (upright) when there is none.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 {
+			// Start of scan or end of image: no more metadata.
+			return 1
+		}
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		if size < 2 || i+2+size > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+size]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + size
+	}
+	return 1
+}
+
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for n := 0; n < entries; n++ {
+		e := ifd + 2 + n*12
+		if e+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[e:]) == 0x0112 {
+			o := int(order.Uint16(tiff[e+8:]))
+			if o >= 1 && o <= 8 {
+				return o
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// orient transforms img so that it displays upright for the given EXIF
+// orientation.
+func orient(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	// Orientations 5-8 swap width and height.
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			dst.Set(x, y, img.At(b.Min.X+sx, b.Min.Y+sy))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,140 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// withExif inserts an APP1 segment carrying an orientation tag and a GPS-ish
+// marker string right after the JPEG start-of-image marker.
+func withExif(t *testing.T, data []byte, orientation uint16) []byte {
+	t.Helper()
+	tiff := []byte{
+		'M', 'M', 0, 42, 0, 0, 0, 8, // big-endian header, IFD0 at offset 8
+		0, 1, // one entry
+		0x01, 0x12, 0, 3, 0, 0, 0, 1, byte(orientation >> 8), byte(orientation), 0, 0,
+		0, 0, 0, 0, // no next IFD
+	}
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	payload = append(payload, []byte("SECRET-GPS")...)
+	size := len(payload) + 2
+	segment := append([]byte{0xFF, 0xE1, byte(size >> 8), byte(size)}, payload...)
+	out := append([]byte{}, data[:2]...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+func encodeJPEG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		img.Set(x, 0, color.RGBA{255, 0, 0, 255})
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("Error encoding JPEG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcessJPEGStripsExifAndRotates(t *testing.T) {
+	data := withExif(t, encodeJPEG(t, 800, 600), 6)
+	if got := jpegOrientation(data); got != 6 {
+		t.Fatalf("jpegOrientation returned %d, want 6", got)
+	}
+
+	img, err := Process(data)
+	if err != nil {
+		t.Fatalf("Process returned error: %v", err)
+	}
+	if img.ContentType != "image/jpeg" {
+		t.Fatalf("ContentType = %q", img.ContentType)
+	}
+	if bytes.Contains(img.Data, []byte("Exif")) || bytes.Contains(img.Data, []byte("SECRET-GPS")) {
+		t.Fatalf("Processed image still contains EXIF data")
+	}
+	if img.Width != 600 || img.Height != 800 {
+		t.Fatalf("Expected rotated 600x800 image, got %dx%d", img.Width, img.Height)
+	}
+	thumb, err := jpeg.DecodeConfig(bytes.NewReader(img.Thumbnail))
+	if err != nil {
+		t.Fatalf("Error decoding thumbnail: %v", err)
+	}
+	if thumb.Width != 300 || thumb.Height != ThumbnailSize {
+		t.Fatalf("Expected 300x%d thumbnail, got %dx%d", ThumbnailSize, thumb.Width, thumb.Height)
+	}
+}
+
+func TestProcessSmallPNGKeepsSize(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 40, 20)))
+
+	img, err := Process(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Process returned error: %v", err)
+	}
+	thumb, err := png.DecodeConfig(bytes.NewReader(img.Thumbnail))
+	if err != nil {
+		t.Fatalf("Error decoding thumbnail: %v", err)
+	}
+	if img.ThumbnailContentType != "image/png" || thumb.Width != 40 || thumb.Height != 20 {
+		t.Fatalf("Unexpected thumbnail %s %dx%d", img.ThumbnailContentType, thumb.Width, thumb.Height)
+	}
+}
+
+func encodeGIF(t *testing.T, frames, w, h int) []byte {
+	t.Helper()
+	g := &gif.GIF{}
+	for i := 0; i < frames; i++ {
+		g.Image = append(g.Image, image.NewPaletted(image.Rect(0, 0, w, h), palette.Plan9))
+		g.Delay = append(g.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatalf("Error encoding GIF: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcessGIFFrameLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		frames  int
+		w, h    int
+		wantErr bool
+	}{
+		{"small animation", 3, 20, 10, false},
+		{"too many frames", MaxFrames + 1, 1, 1, true},
+		{"too many pixels", 5, 3000, 3000, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := encodeGIF(t, tt.frames, tt.w, tt.h)
+			frames, pixels, err := gifFrames(data)
+			if err != nil || frames != tt.frames || pixels != tt.frames*tt.w*tt.h {
+				t.Fatalf("gifFrames = %d, %d, %v; want %d frames", frames, pixels, err, tt.frames)
+			}
+			img, err := Process(data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Process returned %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (img.ContentType != "image/gif" || img.Width != tt.w) {
+				t.Errorf("Unexpected image %s %dx%d", img.ContentType, img.Width, img.Height)
+			}
+		})
+	}
+}
+
+func TestProcessRejectsUnsupportedTypes(t *testing.T) {
+	_, err := Process([]byte("<html><body>not an image</body></html>"))
+	if !errors.Is(err, ErrUnsupportedType) {
+		t.Fatalf("Process returned %v, want ErrUnsupportedType", err)
+	}
+}
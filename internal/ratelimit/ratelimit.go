@@ -0,0 +1,176 @@
+// Package ratelimit limits how often clients can call HTTP handlers, using a
+// token bucket per client and policy.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Policy allows bursts of up to Limit requests, refilling the bucket at
+// Limit requests per Period. Name keeps the buckets of different policies
+// apart when they share a key.
+type Policy struct {
+	Name   string
+	Limit  int
+	Period time.Duration
+}
+
+// rate is the number of tokens added per second.
+func (p Policy) rate() float64 {
+	return float64(p.Limit) / p.Period.Seconds()
+}
+
+// Result is the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next token, when not Allowed.
+	RetryAfter time.Duration
+}
+
+// Store holds the buckets. MemoryStore keeps them in the process; a store
+// backed by a shared database lets several server instances enforce one
+// limit between them.
+type Store interface {
+	// Take removes a token from the key's bucket for p if one is available.
+	Take(ctx context.Context, key string, p Policy, now time.Time) (Result, error)
+}
+
+// KeyFunc picks the bucket for a request, such as the client's IP address,
+// user ID or API key. Requests with an empty key aren't limited.
+type KeyFunc func(r *http.Request) string
+
+// Limiter turns policies into middleware sharing one store.
+type Limiter struct {
+	Store Store
+	// Now returns the current time. It defaults to time.Now.
+	Now func() time.Time
+}
+
+func New(store Store) *Limiter {
+	return &Limiter{Store: store}
+}
+
+// Middleware limits requests to p, with one bucket per key. It sets the
+// RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy
+// headers on every response, and rejects requests over the limit with 429
+// Too Many Requests and a Retry-After header. If the store fails, requests
+// are let through.
+func (l *Limiter) Middleware(p Policy, key KeyFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k := key(r)
+			if k == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			now := time.Now()
+			if l.Now != nil {
+				now = l.Now()
+			}
+			res, err := l.Store.Take(r.Context(), p.Name+":"+k, p, now)
+			if err != nil {
+				slog.ErrorContext(r.Context(), "Error checking rate limit", "policy", p.Name, "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			h.Set("RateLimit-Reset", strconv.Itoa(seconds(res.Reset)))
+			h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", p.Limit, seconds(p.Period)))
+			if !res.Allowed {
+				h.Set("Retry-After", strconv.Itoa(seconds(res.RetryAfter)))
+				h.Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusTooManyRequests)
+				w.Write([]byte(`{"error":"Too many requests"}`))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// seconds rounds d up to whole seconds, as the headers require.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// sweepInterval is how often MemoryStore drops full buckets, which behave
+// the same as missing ones.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	policy  Policy
+}
+
+// refill brings the bucket up to date.
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.updated).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(b.policy.Limit), b.tokens+elapsed*b.policy.rate())
+		b.updated = now
+	}
+}
+
+// MemoryStore keeps buckets in memory, so each server instance has its own
+// limits.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*bucket{}}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, p Policy, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.lastSweep) > sweepInterval {
+		s.sweepLocked(now)
+	}
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(p.Limit), updated: now, policy: p}
+		s.buckets[key] = b
+	}
+	b.refill(now)
+	res := Result{Limit: p.Limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = secondsDuration((1 - b.tokens) / p.rate())
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = secondsDuration((float64(p.Limit) - b.tokens) / p.rate())
+	return res, nil
+}
+
+func (s *MemoryStore) sweepLocked(now time.Time) {
+	for key, b := range s.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.policy.Limit) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
+
+func secondsDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var login = Policy{Name: "login", Limit: 3, Period: time.Minute}
+
+func TestMemoryStoreRefills(t *testing.T) {
+	s := NewMemoryStore()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		res, err := s.Take(ctx, "a", login, now)
+		if err != nil || !res.Allowed {
+			t.Fatalf("Take %d = %+v, %v; want allowed", i, res, err)
+		}
+	}
+	res, _ := s.Take(ctx, "a", login, now)
+	if res.Allowed || res.RetryAfter != 20*time.Second || res.Remaining != 0 {
+		t.Fatalf("Take over the limit = %+v, want denied with a 20s retry", res)
+	}
+	if res, _ := s.Take(ctx, "b", login, now); !res.Allowed {
+		t.Fatal("Another key shared the bucket")
+	}
+	res, _ = s.Take(ctx, "a", login, now.Add(20*time.Second))
+	if !res.Allowed || res.Remaining != 0 || res.Reset != time.Minute {
+		t.Fatalf("Take after one refill = %+v, want allowed with a 1m reset", res)
+	}
+}
+
+func TestMemoryStoreSweepsFullBuckets(t *testing.T) {
+	s := NewMemoryStore()
+	now := time.Now()
+	s.Take(context.Background(), "a", login, now)
+	s.Take(context.Background(), "b", login, now.Add(2*time.Minute))
+	if _, ok := s.buckets["a"]; ok {
+		t.Fatal("Full bucket wasn't swept")
+	}
+	if len(s.buckets) != 1 {
+		t.Fatalf("Store has %d buckets, want 1", len(s.buckets))
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	now := time.Now()
+	l := &Limiter{Store: NewMemoryStore(), Now: func() time.Time { return now }}
+	key := func(r *http.Request) string { return r.Header.Get("X-Client") }
+	h := l.Middleware(login, key)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	do := func(client string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/login", nil)
+		req.Header.Set("X-Client", client)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do("a")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("First request got %d", rec.Code)
+	}
+	want := map[string]string{
+		"RateLimit-Limit":     "3",
+		"RateLimit-Remaining": "2",
+		"RateLimit-Reset":     "20",
+		"RateLimit-Policy":    "3;w=60",
+	}
+	for name, value := range want {
+		if got := rec.Header().Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+	do("a")
+	do("a")
+	rec = do("a")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "20" {
+		t.Fatalf("Request over the limit got %d with Retry-After %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	for i := 0; i < 5; i++ {
+		if rec := do(""); rec.Code != http.StatusNoContent || rec.Header().Get("RateLimit-Limit") != "" {
+			t.Fatalf("Request without a key was limited: %d", rec.Code)
+		}
+	}
+}
@@ -0,0 +1,364 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// Topics a client can subscribe to. Per-user topics (notifications and
+// direct messages) are only ever delivered to connections authenticated as
+// that user.
+const (
+	TopicTimeline      = "timeline"
+	TopicNotifications = "notifications"
+	TopicMessages      = "messages"
+
+	timelinePrefix = "timeline:"
+	threadPrefix   = "thread:"
+)
+
+var ErrHubClosed = errors.New("hub is shutting down")
+
+// Options configures the heartbeat and buffering behaviour of a Hub.
+type Options struct {
+	// SendBuffer is the number of outgoing messages queued per connection
+	// before the connection is considered too slow and dropped.
+	SendBuffer     int
+	PingInterval   time.Duration
+	PongWait       time.Duration
+	WriteWait      time.Duration
+	MaxMessageSize int64
+}
+
+func DefaultOptions() Options {
+	return Options{
+		SendBuffer:     64,
+		PingInterval:   30 * time.Second,
+		PongWait:       60 * time.Second,
+		WriteWait:      10 * time.Second,
+		MaxMessageSize: 4096,
+	}
+}
+
+// Hub tracks live WebSocket connections and the topics they subscribe to.
+type Hub struct {
+	opts     Options
+	upgrader websocket.Upgrader
+
+	mu      sync.RWMutex
+	clients map[*client]struct{}
+	closing bool
+	wg      sync.WaitGroup
+}
+
+func NewHub(opts Options) *Hub {
+	if opts.SendBuffer <= 0 {
+		opts.SendBuffer = DefaultOptions().SendBuffer
+	}
+	return &Hub{
+		opts:    opts,
+		clients: map[*client]struct{}{},
+	}
+}
+
+// TimelineTopic is the topic carrying chirps written by a single author.
+func TimelineTopic(userID uuid.UUID) string {
+	return timelinePrefix + userID.String()
+}
+
+// ThreadTopic is the topic carrying updates to a single chirp.
+func ThreadTopic(chirpID uuid.UUID) string {
+	return threadPrefix + chirpID.String()
+}
+
+func validTopic(topic string) bool {
+	switch {
+	case topic == TopicTimeline, topic == TopicNotifications, topic == TopicMessages:
+		return true
+	case strings.HasPrefix(topic, timelinePrefix):
+		_, err := uuid.Parse(strings.TrimPrefix(topic, timelinePrefix))
+		return err == nil
+	case strings.HasPrefix(topic, threadPrefix):
+		_, err := uuid.Parse(strings.TrimPrefix(topic, threadPrefix))
+		return err == nil
+	}
+	return false
+}
+
+// Message is the envelope for every frame exchanged with a client.
+type Message struct {
+	Type  string `json:"type"`
+	Topic string `json:"topic,omitempty"`
+	Event string `json:"event,omitempty"`
+	Data  any    `json:"data,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+type client struct {
+	hub    *Hub
+	conn   *websocket.Conn
+	userID uuid.UUID
+	send   chan []byte
+	subs   map[string]struct{}
+	closed bool
+}
+
+// ServeWS upgrades the request and registers the connection for userID. The
+// caller is responsible for authenticating the request before calling it.
+func (h *Hub) ServeWS(w http.ResponseWriter, r *http.Request, userID uuid.UUID) error {
+	h.mu.RLock()
+	closing := h.closing
+	h.mu.RUnlock()
+	if closing {
+		http.Error(w, ErrHubClosed.Error(), http.StatusServiceUnavailable)
+		return ErrHubClosed
+	}
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return err
+	}
+	c := &client{
+		hub:    h,
+		conn:   conn,
+		userID: userID,
+		send:   make(chan []byte, h.opts.SendBuffer),
+		subs:   map[string]struct{}{},
+	}
+	h.mu.Lock()
+	if h.closing {
+		h.mu.Unlock()
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"),
+			time.Now().Add(h.opts.WriteWait))
+		conn.Close()
+		return ErrHubClosed
+	}
+	h.clients[c] = struct{}{}
+	h.wg.Add(1)
+	h.mu.Unlock()
+
+	go c.writePump()
+	go c.readPump()
+	return nil
+}
+
+// Publish delivers an event to every connection subscribed to topic.
+func (h *Hub) Publish(topic, event string, data any) {
+	h.PublishExcluding(topic, event, data, nil)
+}
+
+// PublishExcluding is like Publish but skips connections belonging to any of
+// the excluded users, e.g. people who blocked or muted the author.
+func (h *Hub) PublishExcluding(topic, event string, data any, excluded []uuid.UUID) {
+	msg, err := json.Marshal(Message{Type: "event", Topic: topic, Event: event, Data: data})
+	if err != nil {
+		slog.Error("Error marshalling realtime event", "error", err)
+		return
+	}
+	skip := make(map[uuid.UUID]bool, len(excluded))
+	for _, id := range excluded {
+		skip[id] = true
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.clients {
+		if skip[c.userID] {
+			continue
+		}
+		if _, ok := c.subs[topic]; ok {
+			h.enqueueLocked(c, msg)
+		}
+	}
+}
+
+// PublishToUser delivers an event on a per-user topic, such as notifications,
+// to the connections of userID that subscribed to it.
+func (h *Hub) PublishToUser(userID uuid.UUID, topic, event string, data any) {
+	h.PublishToUsers([]uuid.UUID{userID}, topic, event, data)
+}
+
+// PublishToUsers delivers an event only to the connections of the given
+// users that subscribed to topic.
+func (h *Hub) PublishToUsers(userIDs []uuid.UUID, topic, event string, data any) {
+	if len(userIDs) == 0 {
+		return
+	}
+	msg, err := json.Marshal(Message{Type: "event", Topic: topic, Event: event, Data: data})
+	if err != nil {
+		slog.Error("Error marshalling realtime event", "error", err)
+		return
+	}
+	include := make(map[uuid.UUID]bool, len(userIDs))
+	for _, id := range userIDs {
+		include[id] = true
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.clients {
+		if !include[c.userID] {
+			continue
+		}
+		if _, ok := c.subs[topic]; ok {
+			h.enqueueLocked(c, msg)
+		}
+	}
+}
+
+// DisconnectUser closes every connection belonging to userID, such as when
+// the account is suspended. Events already queued are still delivered.
+func (h *Hub) DisconnectUser(userID uuid.UUID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.clients {
+		if c.userID == userID {
+			h.removeLocked(c)
+		}
+	}
+}
+
+// enqueueLocked queues msg without blocking. A connection whose buffer is full
+// is dropped rather than allowed to stall every other subscriber.
+func (h *Hub) enqueueLocked(c *client, msg []byte) {
+	if c.closed {
+		return
+	}
+	select {
+	case c.send <- msg:
+	default:
+		slog.Warn("Dropping slow websocket client", "user_id", c.userID)
+		h.removeLocked(c)
+	}
+}
+
+func (h *Hub) removeLocked(c *client) {
+	if c.closed {
+		return
+	}
+	c.closed = true
+	delete(h.clients, c)
+	close(c.send)
+}
+
+func (h *Hub) remove(c *client) {
+	h.mu.Lock()
+	h.removeLocked(c)
+	h.mu.Unlock()
+}
+
+// Shutdown stops accepting connections and closes the existing ones after
+// their buffered messages have been flushed, or when ctx expires.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.closing = true
+	for c := range h.clients {
+		h.removeLocked(c)
+	}
+	h.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		h.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *client) reply(msg Message) {
+	dat, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	c.hub.mu.Lock()
+	c.hub.enqueueLocked(c, dat)
+	c.hub.mu.Unlock()
+}
+
+func (c *client) readPump() {
+	defer func() {
+		c.hub.remove(c)
+	}()
+	c.conn.SetReadLimit(c.hub.opts.MaxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(c.hub.opts.PongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(c.hub.opts.PongWait))
+	})
+	for {
+		var msg Message
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				c.reply(Message{Type: "error", Error: "invalid message"})
+				continue
+			}
+			return
+		}
+		c.conn.SetReadDeadline(time.Now().Add(c.hub.opts.PongWait))
+		switch msg.Type {
+		case "subscribe":
+			if !validTopic(msg.Topic) {
+				c.reply(Message{Type: "error", Topic: msg.Topic, Error: fmt.Sprintf("unknown topic %q", msg.Topic)})
+				continue
+			}
+			c.hub.mu.Lock()
+			c.subs[msg.Topic] = struct{}{}
+			c.hub.mu.Unlock()
+			c.reply(Message{Type: "subscribed", Topic: msg.Topic})
+		case "unsubscribe":
+			c.hub.mu.Lock()
+			delete(c.subs, msg.Topic)
+			c.hub.mu.Unlock()
+			c.reply(Message{Type: "unsubscribed", Topic: msg.Topic})
+		case "ping":
+			c.reply(Message{Type: "pong"})
+		default:
+			c.reply(Message{Type: "error", Error: fmt.Sprintf("unknown message type %q", msg.Type)})
+		}
+	}
+}
+
+func (c *client) writePump() {
+	ticker := time.NewTicker(c.hub.opts.PingInterval)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+		c.hub.wg.Done()
+	}()
+	for {
+		select {
+		case msg, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(c.hub.opts.WriteWait))
+			if !ok {
+				// The hub closed the channel: either we are draining for
+				// shutdown or the client fell too far behind.
+				c.conn.WriteMessage(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				c.hub.remove(c)
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(c.hub.opts.WriteWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.hub.remove(c)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package realtime
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+func newTestServer(t *testing.T, h *Hub, userID uuid.UUID) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeWS(w, r, userID)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func dial(t *testing.T, srv *httptest.Server) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Error dialing websocket: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	return conn
+}
+
+func expect(t *testing.T, conn *websocket.Conn, msgType string) Message {
+	t.Helper()
+	var msg Message
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("Error reading message: %v", err)
+	}
+	if msg.Type != msgType {
+		t.Fatalf("Expected message type %q, got %+v", msgType, msg)
+	}
+	return msg
+}
+
+func TestSubscribeAndPublish(t *testing.T) {
+	h := NewHub(DefaultOptions())
+	conn := dial(t, newTestServer(t, h, uuid.New()))
+
+	conn.WriteJSON(Message{Type: "subscribe", Topic: TopicTimeline})
+	expect(t, conn, "subscribed")
+
+	h.Publish(TopicTimeline, "chirp.created", map[string]string{"body": "hello"})
+	h.Publish(ThreadTopic(uuid.New()), "chirp.deleted", nil)
+	msg := expect(t, conn, "event")
+	if msg.Topic != TopicTimeline || msg.Event != "chirp.created" {
+		t.Fatalf("Unexpected event: %+v", msg)
+	}
+
+	conn.WriteJSON(Message{Type: "unsubscribe", Topic: TopicTimeline})
+	expect(t, conn, "unsubscribed")
+	h.Publish(TopicTimeline, "chirp.created", nil)
+	conn.WriteJSON(Message{Type: "ping"})
+	expect(t, conn, "pong")
+}
+
+func TestSubscribeUnknownTopic(t *testing.T) {
+	h := NewHub(DefaultOptions())
+	conn := dial(t, newTestServer(t, h, uuid.New()))
+
+	conn.WriteJSON(Message{Type: "subscribe", Topic: "thread:not-a-uuid"})
+	expect(t, conn, "error")
+}
+
+func TestPublishToUser(t *testing.T) {
+	h := NewHub(DefaultOptions())
+	alice, bob := uuid.New(), uuid.New()
+	aliceConn := dial(t, newTestServer(t, h, alice))
+	bobConn := dial(t, newTestServer(t, h, bob))
+	for _, conn := range []*websocket.Conn{aliceConn, bobConn} {
+		conn.WriteJSON(Message{Type: "subscribe", Topic: TopicNotifications})
+		expect(t, conn, "subscribed")
+	}
+
+	h.PublishToUser(bob, TopicNotifications, "notification.created", nil)
+	expect(t, bobConn, "event")
+
+	aliceConn.WriteJSON(Message{Type: "ping"})
+	expect(t, aliceConn, "pong")
+}
+
+func TestPublishToUsers(t *testing.T) {
+	h := NewHub(DefaultOptions())
+	alice, bob := uuid.New(), uuid.New()
+	aliceConn := dial(t, newTestServer(t, h, alice))
+	bobConn := dial(t, newTestServer(t, h, bob))
+	for _, conn := range []*websocket.Conn{aliceConn, bobConn} {
+		conn.WriteJSON(Message{Type: "subscribe", Topic: TopicTimeline})
+		expect(t, conn, "subscribed")
+	}
+
+	h.PublishExcluding(TopicTimeline, "chirp.created", "full", []uuid.UUID{bob})
+	h.PublishToUsers([]uuid.UUID{bob}, TopicTimeline, "chirp.created", "placeholder")
+	if msg := expect(t, aliceConn, "event"); msg.Data != "full" {
+		t.Errorf("alice got %v, want the full event", msg.Data)
+	}
+	if msg := expect(t, bobConn, "event"); msg.Data != "placeholder" {
+		t.Errorf("bob got %v, want the placeholder", msg.Data)
+	}
+}
+
+func TestDisconnectUser(t *testing.T) {
+	h := NewHub(DefaultOptions())
+	alice, bob := uuid.New(), uuid.New()
+	aliceConn := dial(t, newTestServer(t, h, alice))
+	bobConn := dial(t, newTestServer(t, h, bob))
+	aliceConn.WriteJSON(Message{Type: "ping"})
+	expect(t, aliceConn, "pong")
+
+	h.DisconnectUser(alice)
+	_, _, err := aliceConn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Fatalf("Expected going away close, got %v", err)
+	}
+	bobConn.WriteJSON(Message{Type: "ping"})
+	expect(t, bobConn, "pong")
+}
+
+func TestShutdownDrains(t *testing.T) {
+	h := NewHub(DefaultOptions())
+	srv := newTestServer(t, h, uuid.New())
+	conn := dial(t, srv)
+	conn.WriteJSON(Message{Type: "subscribe", Topic: TopicTimeline})
+	expect(t, conn, "subscribed")
+
+	h.Publish(TopicTimeline, "chirp.created", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := h.Shutdown(ctx); err != nil {
+		t.Fatalf("Error shutting down hub: %v", err)
+	}
+
+	// Buffered events are flushed before the close frame.
+	expect(t, conn, "event")
+	_, _, err := conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Fatalf("Expected going away close, got %v", err)
+	}
+
+	if _, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil); err == nil {
+		t.Fatalf("Expected dial to fail after shutdown")
+	}
+}
@@ -0,0 +1,189 @@
+// Package spam scores new chirps for signs of spam and flooding.
+package spam
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Action is what to do with a chirp after scoring it.
+type Action string
+
+const (
+	Allow Action = "allow"
+	// Delay publishes the chirp after Config.DelayFor.
+	Delay Action = "delay"
+	// Queue holds the chirp until a moderator reviews it.
+	Queue Action = "queue"
+	// Reject refuses the chirp outright.
+	Reject Action = "reject"
+)
+
+// Reasons reported in a Verdict.
+const (
+	ReasonDuplicate  = "duplicate"
+	ReasonBurst      = "burst"
+	ReasonLinks      = "links"
+	ReasonNewAccount = "new_account"
+)
+
+// Config holds the weight of each signal and the score at which each action
+// kicks in. A threshold of zero disables its action.
+type Config struct {
+	// DuplicateScore is added for every earlier chirp by the same author with
+	// the same normalized body inside DuplicateWindow.
+	DuplicateWindow time.Duration
+	DuplicateScore  int
+	// BurstScore is added once the author has posted BurstLimit chirps
+	// inside BurstWindow.
+	BurstWindow time.Duration
+	BurstLimit  int
+	BurstScore  int
+	// LinkScore is added when a chirp has more than MaxLinks links, or when
+	// links make up more than MaxLinkDensity of its words.
+	MaxLinks       int
+	MaxLinkDensity float64
+	LinkScore      int
+	// NewAccountScore is added for accounts younger than NewAccountAge.
+	NewAccountAge   time.Duration
+	NewAccountScore int
+
+	DelayScore  int
+	QueueScore  int
+	RejectScore int
+	DelayFor    time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		DuplicateWindow: 24 * time.Hour,
+		DuplicateScore:  35,
+		BurstWindow:     time.Minute,
+		BurstLimit:      5,
+		BurstScore:      40,
+		MaxLinks:        3,
+		MaxLinkDensity:  0.5,
+		LinkScore:       25,
+		NewAccountAge:   7 * 24 * time.Hour,
+		NewAccountScore: 15,
+		DelayScore:      50,
+		QueueScore:      70,
+		RejectScore:     100,
+		DelayFor:        10 * time.Minute,
+	}
+}
+
+// Validate checks that the windows are usable and that the enabled
+// thresholds rise from delay to queue to reject.
+func (c Config) Validate() error {
+	if c.DuplicateWindow <= 0 || c.BurstWindow <= 0 {
+		return errors.New("spam: windows must be positive")
+	}
+	if c.DelayScore < 0 || c.QueueScore < 0 || c.RejectScore < 0 {
+		return errors.New("spam: thresholds can't be negative")
+	}
+	if c.DelayScore > 0 && c.DelayFor <= 0 {
+		return errors.New("spam: a delay threshold needs a positive delay")
+	}
+	last := 0
+	for _, threshold := range []int{c.DelayScore, c.QueueScore, c.RejectScore} {
+		if threshold == 0 {
+			continue
+		}
+		if threshold <= last {
+			return errors.New("spam: thresholds must rise from delay to queue to reject")
+		}
+		last = threshold
+	}
+	return nil
+}
+
+// Signals describes a new chirp and its author's recent activity.
+type Signals struct {
+	Body       string
+	AccountAge time.Duration
+	// Duplicates counts the author's chirps with the same Hash inside
+	// DuplicateWindow.
+	Duplicates int
+	// RecentChirps counts the author's chirps inside BurstWindow.
+	RecentChirps int
+}
+
+type Verdict struct {
+	Score   int
+	Action  Action
+	Reasons []string
+}
+
+// Score weighs the signals and picks the strongest action whose threshold
+// the total reaches.
+func (c Config) Score(s Signals) Verdict {
+	v := Verdict{Action: Allow, Reasons: []string{}}
+	add := func(score int, reason string) {
+		if score > 0 {
+			v.Score += score
+			v.Reasons = append(v.Reasons, reason)
+		}
+	}
+	if s.Duplicates > 0 {
+		add(s.Duplicates*c.DuplicateScore, ReasonDuplicate)
+	}
+	if c.BurstLimit > 0 && s.RecentChirps >= c.BurstLimit {
+		add(c.BurstScore, ReasonBurst)
+	}
+	links, words := countLinks(s.Body)
+	if links > 0 && (links > c.MaxLinks || float64(links)/float64(words) > c.MaxLinkDensity) {
+		add(c.LinkScore, ReasonLinks)
+	}
+	if s.AccountAge < c.NewAccountAge {
+		add(c.NewAccountScore, ReasonNewAccount)
+	}
+
+	switch {
+	case c.RejectScore > 0 && v.Score >= c.RejectScore:
+		v.Action = Reject
+	case c.QueueScore > 0 && v.Score >= c.QueueScore:
+		v.Action = Queue
+	case c.DelayScore > 0 && v.Score >= c.DelayScore:
+		v.Action = Delay
+	}
+	return v
+}
+
+var linkPattern = regexp.MustCompile(`^(https?://|www\.)\S`)
+
+// countLinks returns the number of links and words in body.
+func countLinks(body string) (links, words int) {
+	for _, word := range strings.Fields(body) {
+		words++
+		if linkPattern.MatchString(strings.ToLower(word)) {
+			links++
+		}
+	}
+	return links, words
+}
+
+// Normalize lowercases body and drops punctuation and symbols, so chirps
+// that differ only in case, spacing or decoration compare equal.
+func Normalize(body string) string {
+	fields := strings.FieldsFunc(strings.ToLower(body), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	return strings.Join(fields, " ")
+}
+
+// Hash returns the hex SHA-256 of the normalized body, or "" when nothing is
+// left after normalizing, as with media-only chirps.
+func Hash(body string) string {
+	normalized := Normalize(body)
+	if normalized == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,97 @@
+package spam
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+const established = 30 * 24 * time.Hour
+
+func TestHashIgnoresCaseAndPunctuation(t *testing.T) {
+	a := Hash("Buy   cheap followers!!! ")
+	b := Hash("buy cheap... FOLLOWERS")
+	if a == "" || a != b {
+		t.Fatalf("Hash(%q) = %q, Hash(%q) = %q, want equal non-empty hashes", "Buy   cheap followers!!! ", a, "buy cheap... FOLLOWERS", b)
+	}
+	if Hash("buy cheap followers now") == a {
+		t.Fatal("Different bodies hashed the same")
+	}
+	if h := Hash(" ?! "); h != "" {
+		t.Fatalf("Hash of an empty body = %q, want empty", h)
+	}
+}
+
+func TestScore(t *testing.T) {
+	cfg := DefaultConfig()
+	tests := []struct {
+		name    string
+		signals Signals
+		action  Action
+		reasons []string
+	}{
+		{
+			name:    "ordinary chirp",
+			signals: Signals{Body: "Lunch was great today", AccountAge: established, RecentChirps: 1},
+			action:  Allow,
+			reasons: []string{},
+		},
+		{
+			name:    "new account posting a link",
+			signals: Signals{Body: "https://example.com", AccountAge: time.Hour},
+			action:  Allow,
+			reasons: []string{ReasonLinks, ReasonNewAccount},
+		},
+		{
+			name:    "one repeat from a new account",
+			signals: Signals{Body: "hello", AccountAge: time.Hour, Duplicates: 1},
+			action:  Delay,
+			reasons: []string{ReasonDuplicate, ReasonNewAccount},
+		},
+		{
+			name:    "two repeats",
+			signals: Signals{Body: "hello", AccountAge: established, Duplicates: 2},
+			action:  Queue,
+			reasons: []string{ReasonDuplicate},
+		},
+		{
+			name:    "link flood",
+			signals: Signals{Body: "see https://a.example www.b.example", AccountAge: time.Hour, Duplicates: 2, RecentChirps: 5},
+			action:  Reject,
+			reasons: []string{ReasonDuplicate, ReasonBurst, ReasonLinks, ReasonNewAccount},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := cfg.Score(tt.signals)
+			if v.Action != tt.action || !slices.Equal(v.Reasons, tt.reasons) {
+				t.Fatalf("Score = %+v, want action %s with reasons %v", v, tt.action, tt.reasons)
+			}
+		})
+	}
+}
+
+func TestScoreDisabledThresholds(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.RejectScore = 0
+	v := cfg.Score(Signals{Body: "hi", Duplicates: 5})
+	if v.Action != Queue {
+		t.Fatalf("Score with reject disabled = %s, want %s", v.Action, Queue)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Fatalf("DefaultConfig is invalid: %v", err)
+	}
+	cfg := DefaultConfig()
+	cfg.QueueScore = cfg.RejectScore
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("Validate accepted a queue threshold equal to the reject threshold")
+	}
+	cfg = DefaultConfig()
+	cfg.QueueScore = 0
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate rejected a disabled threshold: %v", err)
+	}
+}
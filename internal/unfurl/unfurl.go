@@ -0,0 +1,292 @@
+// Package unfurl fetches OpenGraph and Twitter card metadata for links.
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	// DefaultMaxBytes caps how much of a page is read. Metadata lives in the
+	// <head>, so there's no need to download the whole document.
+	DefaultMaxBytes = 512 << 10
+	DefaultTimeout  = 5 * time.Second
+	maxRedirects    = 5
+	maxFieldLength  = 500
+)
+
+var (
+	ErrForbiddenAddress = errors.New("unfurl: address is not publicly routable")
+	ErrNotHTML          = errors.New("unfurl: response is not HTML")
+)
+
+// Preview is the metadata shown alongside a link.
+type Preview struct {
+	URL         string
+	Title       string
+	Description string
+	ImageURL    string
+	SiteName    string
+}
+
+// Fetcher retrieves link previews. The zero value is not usable; create one
+// with NewFetcher, or set Client directly in tests to reach httptest servers.
+type Fetcher struct {
+	Client    *http.Client
+	MaxBytes  int64
+	UserAgent string
+}
+
+// NewFetcher returns a Fetcher whose client refuses to connect to private,
+// loopback and otherwise internal addresses.
+func NewFetcher() *Fetcher {
+	return &Fetcher{
+		Client:    NewSafeClient(DefaultTimeout),
+		MaxBytes:  DefaultMaxBytes,
+		UserAgent: "ChirpyBot/1.0 (+link previews)",
+	}
+}
+
+// NewSafeClient returns an HTTP client for fetching untrusted URLs. The
+// address check runs on every connection after DNS resolution, so redirects
+// and DNS rebinding can't reach internal services either.
+func NewSafeClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !IsPublic(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", ErrForbiddenAddress, addrPort.Addr())
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// A proxy from the environment would make the connection
+			// check meaningless, so always dial directly.
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   timeout,
+			ResponseHeaderTimeout: timeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       30 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errors.New("unfurl: too many redirects")
+			}
+			return checkScheme(req.URL)
+		},
+	}
+}
+
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+// IsPublic reports whether addr is a globally routable unicast address.
+func IsPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, p := range reservedPrefixes {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+func checkScheme(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unfurl: unsupported scheme %q", u.Scheme)
+	}
+	return nil
+}
+
+// Fetch downloads rawURL and extracts its preview metadata, falling back to
+// the <title> and description meta tags when there are no OpenGraph tags.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (Preview, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Preview{}, err
+	}
+	if err := checkScheme(u); err != nil {
+		return Preview{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return Preview{}, err
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	if f.UserAgent != "" {
+		req.Header.Set("User-Agent", f.UserAgent)
+	}
+	client := f.Client
+	if client == nil {
+		client = NewSafeClient(DefaultTimeout)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Preview{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Preview{}, fmt.Errorf("unfurl: %s returned %s", rawURL, resp.Status)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return Preview{}, fmt.Errorf("%w: %s", ErrNotHTML, mediaType)
+	}
+	maxBytes := f.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	p := parse(io.LimitReader(resp.Body, maxBytes))
+	// Relative image URLs resolve against wherever redirects ended up.
+	p.URL = resp.Request.URL.String()
+	if p.ImageURL != "" {
+		img, err := resp.Request.URL.Parse(p.ImageURL)
+		if err != nil || checkScheme(img) != nil {
+			p.ImageURL = ""
+		} else {
+			p.ImageURL = img.String()
+		}
+	}
+	return p, nil
+}
+
+// parse reads meta tags until the end of <head>. Truncated documents are
+// fine: whatever was found before the cut-off is returned.
+func parse(r io.Reader) Preview {
+	var p, fallback Preview
+	z := html.NewTokenizer(r)
+	inTitle := false
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return merge(p, fallback)
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			switch tok.Data {
+			case "title":
+				inTitle = true
+			case "body":
+				return merge(p, fallback)
+			case "meta":
+				var key, content string
+				for _, a := range tok.Attr {
+					switch a.Key {
+					case "property", "name":
+						if key == "" {
+							key = strings.ToLower(a.Val)
+						}
+					case "content":
+						content = clean(a.Val)
+					}
+				}
+				switch key {
+				case "og:title":
+					p.Title = content
+				case "og:description":
+					p.Description = content
+				case "og:image", "og:image:url":
+					if p.ImageURL == "" {
+						p.ImageURL = content
+					}
+				case "og:site_name":
+					p.SiteName = content
+				case "twitter:title":
+					fallback.Title = content
+				case "twitter:description", "description":
+					if fallback.Description == "" || key == "twitter:description" {
+						fallback.Description = content
+					}
+				case "twitter:image", "twitter:image:src":
+					fallback.ImageURL = content
+				}
+			}
+		case html.TextToken:
+			if inTitle && fallback.Title == "" {
+				fallback.Title = clean(string(z.Text()))
+			}
+		case html.EndTagToken:
+			switch tok := z.Token(); tok.Data {
+			case "title":
+				inTitle = false
+			case "head":
+				return merge(p, fallback)
+			}
+		}
+	}
+}
+
+func merge(p, fallback Preview) Preview {
+	if p.Title == "" {
+		p.Title = fallback.Title
+	}
+	if p.Description == "" {
+		p.Description = fallback.Description
+	}
+	if p.ImageURL == "" {
+		p.ImageURL = fallback.ImageURL
+	}
+	return p
+}
+
+// clean collapses whitespace and truncates overly long values.
+func clean(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > maxFieldLength {
+		s = string(r[:maxFieldLength])
+	}
+	return s
+}
+
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+// ExtractURLs returns the distinct http(s) URLs in text, in order, ignoring
+// trailing punctuation.
+func ExtractURLs(text string) []string {
+	urls := []string{}
+	for _, m := range urlPattern.FindAllString(text, -1) {
+		m = strings.TrimRight(m, ".,;:!?)]}'")
+		u, err := url.Parse(m)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		if !slices.Contains(urls, m) {
+			urls = append(urls, m)
+		}
+	}
+	return urls
+}
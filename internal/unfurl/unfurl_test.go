@@ -0,0 +1,163 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const page = `<!doctype html>
+<html><head>
+<title>Fallback title</title>
+<meta property="og:title" content="  The   Title ">
+<meta property="og:description" content="A description">
+<meta property="og:image" content="/img/cover.png">
+<meta property="og:site_name" content="Example">
+</head><body><meta property="og:title" content="ignored"></body></html>`
+
+func testFetcher(srv *httptest.Server) *Fetcher {
+	return &Fetcher{Client: srv.Client(), MaxBytes: DefaultMaxBytes}
+}
+
+func TestFetchOpenGraph(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/article", http.StatusMovedPermanently)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(page))
+	}))
+	defer srv.Close()
+
+	p, err := testFetcher(srv).Fetch(context.Background(), srv.URL+"/old")
+	if err != nil {
+		t.Fatalf("Fetch returned error: %v", err)
+	}
+	want := Preview{
+		URL:         srv.URL + "/article",
+		Title:       "The Title",
+		Description: "A description",
+		ImageURL:    srv.URL + "/img/cover.png",
+		SiteName:    "Example",
+	}
+	if p != want {
+		t.Fatalf("Fetch returned %+v\nwant %+v", p, want)
+	}
+}
+
+func TestFetchFallsBackToTwitterAndTitle(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Plain title</title>
+<meta name="description" content="Plain description">
+<meta name="twitter:image" content="https://cdn.example.com/a.jpg"></head></html>`))
+	}))
+	defer srv.Close()
+
+	p, err := testFetcher(srv).Fetch(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("Fetch returned error: %v", err)
+	}
+	if p.Title != "Plain title" || p.Description != "Plain description" || p.ImageURL != "https://cdn.example.com/a.jpg" {
+		t.Fatalf("Unexpected preview %+v", p)
+	}
+}
+
+func TestFetchRejectsNonHTML(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write([]byte("binary"))
+	}))
+	defer srv.Close()
+
+	if _, err := testFetcher(srv).Fetch(context.Background(), srv.URL); !errors.Is(err, ErrNotHTML) {
+		t.Fatalf("Fetch returned %v, want ErrNotHTML", err)
+	}
+}
+
+func TestFetchStopsAtSizeCap(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head><!--" + strings.Repeat("x", 4096) + `--><meta property="og:title" content="too far"></head>`))
+	}))
+	defer srv.Close()
+
+	f := testFetcher(srv)
+	f.MaxBytes = 1024
+	p, err := f.Fetch(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("Fetch returned error: %v", err)
+	}
+	if p.Title != "" {
+		t.Fatalf("Fetch read past the size cap: %+v", p)
+	}
+}
+
+func TestFetchTimesOut(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	f := testFetcher(srv)
+	f.Client.Timeout = 50 * time.Millisecond
+	if _, err := f.Fetch(context.Background(), srv.URL); err == nil {
+		t.Fatalf("Fetch returned no error for a hanging server")
+	}
+}
+
+func TestSafeClientRefusesLoopback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(page))
+	}))
+	defer srv.Close()
+
+	f := &Fetcher{Client: NewSafeClient(time.Second)}
+	if _, err := f.Fetch(context.Background(), srv.URL); !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("Fetch returned %v, want ErrForbiddenAddress", err)
+	}
+	if _, err := f.Fetch(context.Background(), "file:///etc/passwd"); err == nil {
+		t.Fatalf("Fetch accepted a file URL")
+	}
+}
+
+func TestIsPublic(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"93.184.216.34":          true,
+		"2606:2800:220:1::":      true,
+		"127.0.0.1":              false,
+		"10.1.2.3":               false,
+		"172.16.0.1":             false,
+		"192.168.1.1":            false,
+		"169.254.169.254":        false,
+		"100.64.0.1":             false,
+		"0.0.0.0":                false,
+		"::1":                    false,
+		"fd00::1":                false,
+		"fe80::1":                false,
+		"::ffff:127.0.0.1":       false,
+		"::ffff:169.254.169.254": false,
+	} {
+		if got := IsPublic(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("IsPublic(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestExtractURLs(t *testing.T) {
+	got := ExtractURLs("see https://example.com/a, and (http://example.org/b). again https://example.com/a ftp://x")
+	want := []string{"https://example.com/a", "http://example.org/b"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("ExtractURLs returned %q, want %q", got, want)
+	}
+}
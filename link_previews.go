@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kien-tn/chirpy/internal/database"
+	"github.com/kien-tn/chirpy/internal/realtime"
+	"github.com/kien-tn/chirpy/internal/unfurl"
+)
+
+const (
+	maxChirpLinks        = 4
+	linkPreviewWorkers   = 4
+	linkPreviewQueueSize = 256
+	linkPreviewTimeout   = 10 * time.Second
+)
+
+type LinkPreview struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	ImageURL    string `json:"image_url"`
+	SiteName    string `json:"site_name"`
+}
+
+type linkPreviewJob struct {
+	chirpID uuid.UUID
+	url     string
+}
+
+// linkPreviewer fetches link metadata in the background so creating a chirp
+// never waits on a third-party site. Previews are cached per URL; chirps pick
+// them up through chirp_links once they are ready.
+type linkPreviewer struct {
+	db      *database.Queries
+	hub     *realtime.Hub
+	fetcher *unfurl.Fetcher
+	jobs    chan linkPreviewJob
+	wg      sync.WaitGroup
+}
+
+func newLinkPreviewer(db *database.Queries, hub *realtime.Hub, fetcher *unfurl.Fetcher) *linkPreviewer {
+	return &linkPreviewer{
+		db:      db,
+		hub:     hub,
+		fetcher: fetcher,
+		jobs:    make(chan linkPreviewJob, linkPreviewQueueSize),
+	}
+}
+
+// Start runs workers until ctx is cancelled. Jobs still queued at that point
+// are dropped; those links simply have no preview.
+func (p *linkPreviewer) Start(ctx context.Context, workers int) {
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-p.jobs:
+					p.process(ctx, job)
+				}
+			}
+		}()
+	}
+}
+
+// Wait blocks until every worker has exited.
+func (p *linkPreviewer) Wait() {
+	p.wg.Wait()
+}
+
+// Enqueue schedules a fetch without blocking. When the queue is full the job
+// is dropped rather than slowing down the request that created the chirp.
+func (p *linkPreviewer) Enqueue(chirpID uuid.UUID, url string) {
+	select {
+	case p.jobs <- linkPreviewJob{chirpID: chirpID, url: url}:
+	default:
+		slog.Warn("Link preview queue is full, skipping", "url", url)
+	}
+}
+
+func (p *linkPreviewer) process(ctx context.Context, job linkPreviewJob) {
+	fresh, err := p.db.LinkPreviewIsFresh(ctx, job.url)
+	if err != nil {
+		slog.Error("Error checking link preview cache", "url", job.url, "error", err)
+		return
+	}
+	if fresh {
+		return
+	}
+	fetchCtx, cancel := context.WithTimeout(ctx, linkPreviewTimeout)
+	defer cancel()
+	// Failures are cached as well so a dead link isn't refetched for every
+	// chirp that mentions it.
+	preview, fetchErr := p.fetcher.Fetch(fetchCtx, job.url)
+	if fetchErr != nil {
+		slog.Info("Error fetching link preview", "url", job.url, "error", fetchErr)
+	}
+	err = p.db.UpsertLinkPreview(ctx, database.UpsertLinkPreviewParams{
+		Url:         job.url,
+		Ok:          fetchErr == nil,
+		Title:       preview.Title,
+		Description: preview.Description,
+		ImageUrl:    preview.ImageURL,
+		SiteName:    preview.SiteName,
+	})
+	if err != nil {
+		slog.Error("Error saving link preview", "url", job.url, "error", err)
+		return
+	}
+	if fetchErr != nil {
+		return
+	}
+	p.hub.Publish(realtime.ThreadTopic(job.chirpID), "chirp.link_preview", map[string]interface{}{
+		"chirp_id": job.chirpID,
+		"preview": LinkPreview{
+			URL:         job.url,
+			Title:       preview.Title,
+			Description: preview.Description,
+			ImageURL:    preview.ImageURL,
+			SiteName:    preview.SiteName,
+		},
+	})
+}
+
+// chirpLinkPreviews loads the ready previews of each chirp, in the order the
+// links appear in the body.
+func (cfg *apiConfig) chirpLinkPreviews(ctx context.Context, chirpIDs []uuid.UUID) (map[uuid.UUID][]LinkPreview, error) {
+	rows, err := cfg.db.GetLinkPreviewsByChirpIDs(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	byChirp := map[uuid.UUID][]LinkPreview{}
+	for _, row := range rows {
+		byChirp[row.ChirpID] = append(byChirp[row.ChirpID], LinkPreview{
+			URL:         row.Url,
+			Title:       row.Title,
+			Description: row.Description,
+			ImageURL:    row.ImageUrl,
+			SiteName:    row.SiteName,
+		})
+	}
+	return byChirp, nil
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+	// Longer or unprintable incoming request IDs are replaced.
+	maxRequestIDLength = 128
+)
+
+// newLogger returns a JSON logger writing to stdout at level: debug, info,
+// warn or error.
+func newLogger(level string) (*slog.Logger, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return nil, err
+	}
+	return slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: l})), nil
+}
+
+type requestLogKey struct{}
+
+// requestLog is a request's ID and logger. userID is filled in by
+// middlewareValidateJWT for the access log.
+type requestLog struct {
+	id     string
+	logger *slog.Logger
+	userID uuid.UUID
+}
+
+// middlewareLog gives each request an ID, taken from its X-Request-ID header
+// or generated, and a logger carrying that ID and the trace ID. Once the handler returns it
+// logs the request with its status, size, latency and user.
+func middlewareLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		logger := slog.Default().With("request_id", id)
+		if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+			logger = logger.With("trace_id", sc.TraceID().String())
+		}
+		rl := &requestLog{id: id, logger: logger}
+		w.Header().Set(requestIDHeader, id)
+		lw := &loggingResponseWriter{ResponseWriter: w, log: rl}
+		next.ServeHTTP(lw, r.WithContext(context.WithValue(r.Context(), requestLogKey{}, rl)))
+
+		attrs := []any{
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", lw.statusCode(),
+			"bytes", lw.bytes,
+			"duration_ms", float64(time.Since(start).Microseconds()) / 1000,
+			"ip", clientIP(r),
+		}
+		if rl.userID != uuid.Nil {
+			attrs = append(attrs, "user_id", rl.userID)
+		}
+		rl.logger.Info("request", attrs...)
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r > unicode.MaxASCII || !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// requestIDFromContext returns the ID middlewareLog gave the request.
+func requestIDFromContext(ctx context.Context) string {
+	if rl, ok := ctx.Value(requestLogKey{}).(*requestLog); ok {
+		return rl.id
+	}
+	return ""
+}
+
+// loggerFromContext returns the request's logger, or the default logger
+// outside of a request.
+func loggerFromContext(ctx context.Context) *slog.Logger {
+	if rl, ok := ctx.Value(requestLogKey{}).(*requestLog); ok {
+		return rl.logger
+	}
+	return slog.Default()
+}
+
+// loggerForWriter returns the logger of the request being answered through w.
+func loggerForWriter(w http.ResponseWriter) *slog.Logger {
+	if lw, ok := w.(*loggingResponseWriter); ok {
+		return lw.log.logger
+	}
+	return slog.Default()
+}
+
+// setRequestUser records the authenticated user in the access log.
+func setRequestUser(ctx context.Context, userID uuid.UUID) {
+	if rl, ok := ctx.Value(requestLogKey{}).(*requestLog); ok {
+		rl.userID = userID
+	}
+}
+
+// loggingResponseWriter records the status and size of a response. It keeps
+// the Flusher and Hijacker of the underlying writer available for streaming
+// responses and websockets.
+type loggingResponseWriter struct {
+	http.ResponseWriter
+	log    *requestLog
+	status int
+	bytes  int
+}
+
+// recordResponse returns w as a loggingResponseWriter, wrapping it if
+// middlewareLog hasn't already.
+func recordResponse(w http.ResponseWriter) *loggingResponseWriter {
+	if lw, ok := w.(*loggingResponseWriter); ok {
+		return lw
+	}
+	return &loggingResponseWriter{ResponseWriter: w, log: &requestLog{logger: slog.Default()}}
+}
+
+func (w *loggingResponseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *loggingResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+func (w *loggingResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *loggingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer doesn't support hijacking")
+	}
+	w.status = http.StatusSwitchingProtocols
+	return h.Hijack()
+}
+
+func (w *loggingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *loggingResponseWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
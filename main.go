@@ -1,26 +1,50 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
 	"strings"
 	"sync/atomic"
+	"syscall"
+	"time"
 
-	"github.com/joho/godotenv"
+	"github.com/google/uuid"
 	"github.com/kien-tn/chirpy/internal/auth"
+	"github.com/kien-tn/chirpy/internal/blob"
+	"github.com/kien-tn/chirpy/internal/config"
 	"github.com/kien-tn/chirpy/internal/database"
+	"github.com/kien-tn/chirpy/internal/ratelimit"
+	"github.com/kien-tn/chirpy/internal/realtime"
+	"github.com/kien-tn/chirpy/internal/spam"
+	"github.com/kien-tn/chirpy/internal/unfurl"
 	_ "github.com/lib/pq"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"gopkg.in/yaml.v3"
 )
 
 type apiConfig struct {
 	fileserverHits atomic.Int32
 	db             *database.Queries
+	dbConn         *sql.DB
+	platform       string
 	secretKey      string
 	polkaKey       string
+	hub            *realtime.Hub
+	blobs          blob.BlobStore
+	exports        blob.BlobStore
+	previews       *linkPreviewer
+	spam           spam.Config
+	limiter        *ratelimit.Limiter
+	metrics        *metrics
 }
 
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
@@ -28,21 +52,12 @@ func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
 		// Increment the fileserverHits counter
 		cfg.fileserverHits.Add(1)
 
-		// Print the current hit count to stdout
-		fmt.Fprintln(os.Stdout, "Hitting:", cfg.fileserverHits.Load())
-
 		// Call the next handler in the chain
 		next.ServeHTTP(w, r)
 	})
 }
 
-func middlewareLog(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		log.Printf("%s %s", r.Method, r.URL.Path)
-		next.ServeHTTP(w, r)
-	})
-}
-func middlewareValidateJWT(next http.Handler) http.Handler {
+func (cfg *apiConfig) middlewareValidateJWT(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Check the Authorization header for a JWT
 		token, err := auth.GetBearerToken(r.Header)
@@ -51,16 +66,76 @@ func middlewareValidateJWT(next http.Handler) http.Handler {
 			return
 		}
 		// Check if the JWT is valid
-		_, err = auth.ValidateJWT(token, os.Getenv("SECRET_KEY"))
+		userID, err := auth.ValidateJWT(token, cfg.secretKey)
 		if err != nil {
 			respondWithError(w, http.StatusUnauthorized, "Invalid token", err)
 			return
 		}
+		setRequestUser(r.Context(), userID)
+		state, ok := cfg.checkAccount(w, r, userID)
+		if !ok {
+			return
+		}
 		// Call the next handler in the chain
-		next.ServeHTTP(w, r)
+		ctx := context.WithValue(r.Context(), userIDKey{}, userID)
+		ctx = context.WithValue(ctx, accountStateKey{}, state)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// checkAccount returns the current state of the account behind a valid
+// token. Tokens stay valid after an account is deleted or suspended, so every
+// authenticated entry point checks the account itself. When the account
+// can't be used it writes the error response and returns false.
+func (cfg *apiConfig) checkAccount(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (string, bool) {
+	u, err := cfg.db.GetUserByID(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && u.DeletedAt.Valid) {
+		respondWithError(w, http.StatusUnauthorized, "Invalid token", err)
+		return "", false
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting user", err)
+		return "", false
+	}
+	state := accountState(u)
+	if state == accountSuspended {
+		respondWithError(w, http.StatusForbidden, suspendedMessage(u), nil)
+		return "", false
+	}
+	return state, true
+}
+
+type userIDKey struct{}
+
+type accountStateKey struct{}
+
+// viewerID identifies the caller of a public endpoint from an optional bearer
+// token, returning uuid.Nil for anonymous requests.
+func (cfg *apiConfig) viewerID(r *http.Request) uuid.UUID {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.Nil
+	}
+	userID, err := auth.ValidateJWT(token, cfg.secretKey)
+	if err != nil {
+		return uuid.Nil
+	}
+	return userID
+}
+
+// userIDFromContext returns the authenticated user set by middlewareValidateJWT.
+func userIDFromContext(ctx context.Context) uuid.UUID {
+	userID, _ := ctx.Value(userIDKey{}).(uuid.UUID)
+	return userID
+}
+
+// accountStateFromContext returns the authenticated user's current account
+// state set by middlewareValidateJWT.
+func accountStateFromContext(ctx context.Context) string {
+	state, _ := ctx.Value(accountStateKey{}).(string)
+	return state
+}
+
 func handlerValidateChirp(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
 		Body        string `json:"body"`
@@ -73,7 +148,7 @@ func handlerValidateChirp(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		// an error will be thrown if the JSON is invalid or has the wrong types
 		// any missing fields will simply have their values in the struct set to their zero value
-		log.Printf("Error decoding parameters: %s", err)
+		loggerFromContext(r.Context()).Info("Error decoding parameters", "error", err)
 		w.WriteHeader(500)
 		// Add a message to the response body "error": "Something went wrong"
 		w.Write([]byte(`{"error": "Something went wrong"}`))
@@ -110,30 +185,118 @@ func maskForbiddenWord(body string, forbiddenWords []string) (bool, string) {
 	return containsForbiddenWord, body
 }
 
+// newBlobStore picks where uploaded media is stored: a local directory or
+// any S3-compatible service.
+func newBlobStore(cfg config.Storage) (blob.BlobStore, error) {
+	switch cfg.Backend {
+	case "local":
+		return blob.NewLocalStore(cfg.MediaDir, cfg.MediaBaseURL)
+	case "s3":
+		return &blob.S3Store{
+			Endpoint:  cfg.S3.Endpoint,
+			Region:    cfg.S3.Region,
+			Bucket:    cfg.S3.Bucket,
+			AccessKey: cfg.S3.AccessKeyID,
+			SecretKey: cfg.S3.SecretAccessKey,
+			PublicURL: cfg.S3.PublicURL,
+			Client:    &http.Client{Timeout: 30 * time.Second},
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown blob store %q", cfg.Backend)
+}
+
+// newExportStore keeps data export archives apart from media, which is
+// publicly readable. Archives are only served through signed download links.
+func newExportStore(cfg config.Storage) (blob.BlobStore, error) {
+	switch cfg.Backend {
+	case "local":
+		return blob.NewLocalStore(cfg.ExportDir, "")
+	case "s3":
+		bucket := cfg.S3.ExportBucket
+		if bucket == "" {
+			bucket = cfg.S3.Bucket
+		}
+		return &blob.S3Store{
+			Endpoint:  cfg.S3.Endpoint,
+			Region:    cfg.S3.Region,
+			Bucket:    bucket,
+			AccessKey: cfg.S3.AccessKeyID,
+			SecretKey: cfg.S3.SecretAccessKey,
+			Client:    &http.Client{Timeout: 5 * time.Minute},
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown blob store %q", cfg.Backend)
+}
+
 func main() {
-	godotenv.Load()
-	dbURL := os.Getenv("DB_URL")
-	db, err := sql.Open("postgres", dbURL)
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "YAML or TOML configuration file")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration, with secrets redacted, and exit")
+	flag.Parse()
+	conf, err := config.Load(*configPath)
+	if err != nil {
+		slog.Error("Invalid configuration", "error", err)
+		os.Exit(1)
+	}
+	if *printConfig {
+		if err := yaml.NewEncoder(os.Stdout).Encode(conf.Redacted()); err != nil {
+			slog.Error("Error printing configuration", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+	logger, err := newLogger(conf.LogLevel)
 	if err != nil {
-		log.Fatalf("Error opening database: %s", err)
+		slog.Error("Invalid log level", "error", err)
+		os.Exit(1)
 	}
-	dbQueries := database.New(db)
+	slog.SetDefault(logger)
+	shutdownTracing, err := setupTracing(context.Background(), conf.Tracing)
+	if err != nil {
+		slog.Error("Error setting up tracing", "error", err)
+		os.Exit(1)
+	}
+	db, err := sql.Open("postgres", conf.Database.URL)
+	if err != nil {
+		slog.Error("Error opening database", "error", err)
+		os.Exit(1)
+	}
+	dbQueries := database.NewTraced(db, otel.Tracer(tracerName))
+	blobs, err := newBlobStore(conf.Storage)
+	if err != nil {
+		slog.Error("Error setting up media storage", "error", err)
+		os.Exit(1)
+	}
+	exports, err := newExportStore(conf.Storage)
+	if err != nil {
+		slog.Error("Error setting up export storage", "error", err)
+		os.Exit(1)
+	}
+	serverConfig := conf.Server
+	hub := realtime.NewHub(realtime.DefaultOptions())
 	apiCfg := &apiConfig{
 		db:        dbQueries,
-		secretKey: os.Getenv("SECRET_KEY"),
-		polkaKey:  os.Getenv("POLKA_KEY"),
+		dbConn:    db,
+		platform:  conf.Platform,
+		secretKey: conf.Auth.SecretKey,
+		polkaKey:  conf.Auth.PolkaKey,
+		hub:       hub,
+		blobs:     blobs,
+		exports:   exports,
+		previews:  newLinkPreviewer(dbQueries, hub, unfurl.NewFetcher()),
+		spam:      conf.Spam.Config(),
+		limiter:   ratelimit.New(ratelimit.NewMemoryStore()),
+		metrics:   newMetrics(db),
 	}
 	defer db.Close()
-	fmt.Fprintln(os.Stdout, "Hitting:", apiCfg.fileserverHits.Load())
 	mux := http.NewServeMux()
-	mux.Handle("/app/", http.StripPrefix("/app", middlewareLog(apiCfg.middlewareMetricsInc(http.FileServer(http.Dir("."))))))
-	mux.Handle("GET /api/healthz", middlewareLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	mux.Handle("/app/", http.StripPrefix("/app", apiCfg.middlewareMetricsInc(http.FileServer(http.Dir(".")))))
+	mux.HandleFunc("GET /api/healthz", func(w http.ResponseWriter, r *http.Request) {
 		// ContentType
 		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
-	})))
-	mux.Handle("GET /admin/metrics", middlewareLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	})
+	mux.HandleFunc("GET /admin/metrics", func(w http.ResponseWriter, r *http.Request) {
 		// ContentType
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 
@@ -145,30 +308,135 @@ func main() {
 	<p>Chirpy has been visited %d times!</p>
   </body>
 </html>`, apiCfg.fileserverHits.Load())))
-	})))
+	})
+	mux.Handle("GET /metrics", apiCfg.metrics.handler())
 	mux.HandleFunc("POST /admin/reset", func(w http.ResponseWriter, r *http.Request) {
 		// apiCfg.fileserverHits.Store(0)
 		// w.WriteHeader(http.StatusOK)
 		// w.Write([]byte("OK"))
 		handlerUsersReset(apiCfg, w, r)
 	})
+	mux.Handle("GET /admin/audit-log", apiCfg.middlewareValidateJWT(apiCfg.middlewareRequireAdmin(http.HandlerFunc(apiCfg.handlerGetAuditLog))))
+	mux.Handle("GET /admin/audit-log/export", apiCfg.middlewareValidateJWT(apiCfg.middlewareRequireAdmin(http.HandlerFunc(apiCfg.handlerExportAuditLog))))
+	mux.Handle("GET /admin/reports", apiCfg.middlewareValidateJWT(apiCfg.middlewareRequireModerator(http.HandlerFunc(apiCfg.handlerGetReports))))
+	mux.Handle("GET /admin/reports/{report_id}", apiCfg.middlewareValidateJWT(apiCfg.middlewareRequireModerator(http.HandlerFunc(apiCfg.handlerGetReport))))
+	mux.Handle("POST /admin/reports/{report_id}/assign", apiCfg.middlewareValidateJWT(apiCfg.middlewareRequireModerator(http.HandlerFunc(apiCfg.handlerAssignReport))))
+	mux.Handle("POST /admin/reports/{report_id}/status", apiCfg.middlewareValidateJWT(apiCfg.middlewareRequireModerator(http.HandlerFunc(apiCfg.handlerSetReportStatus))))
+	mux.Handle("POST /admin/reports/{report_id}/resolve", apiCfg.middlewareValidateJWT(apiCfg.middlewareRequireModerator(http.HandlerFunc(apiCfg.handlerResolveReport))))
+	mux.Handle("PUT /admin/users/{user_id}/state", apiCfg.middlewareValidateJWT(apiCfg.middlewareRequireModerator(http.HandlerFunc(apiCfg.handlerSetAccountState))))
+	mux.Handle("PUT /admin/chirps/{chirp_id}/labels", apiCfg.middlewareValidateJWT(apiCfg.middlewareRequireModerator(http.HandlerFunc(apiCfg.handlerLabelChirp))))
 	mux.HandleFunc("POST /api/validate_chirp", handlerValidateChirp)
-	mux.HandleFunc("POST /api/users", func(w http.ResponseWriter, r *http.Request) {
+	mux.Handle("POST /api/users", apiCfg.rateLimit(signupLimit, keyByIP)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		handlerUsers(apiCfg, w, r)
-	})
-	mux.Handle("PUT /api/users", middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerUpdateUsers)))
-	mux.Handle("POST /api/chirps", middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerCreateChip)))
-	mux.HandleFunc("GET /api/chirps", apiCfg.handlerGetAllChirps)
-	mux.HandleFunc("GET /api/chirps/{chirp_id}", apiCfg.handlerGetChirpById)
-	mux.Handle("DELETE /api/chirps/{chirp_id}", middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerDeleteChirp)))
-	mux.HandleFunc("POST /api/login", apiCfg.handlerLogin)
-	mux.HandleFunc("POST /api/refresh", apiCfg.handlerRefreshToken)
-	mux.HandleFunc("POST /api/revoke", apiCfg.handlerRevokeRefreshToken)
-	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.handlerUpdateUserRed)
-	server := &http.Server{
-		Addr:    ":8080",
-		Handler: mux,
-	}
-	server.ListenAndServe()
+	})))
+	mux.Handle("PUT /api/users", apiCfg.rateLimit(authLimit, apiCfg.keyByUser)(apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerUpdateUsers))))
+	mux.Handle("DELETE /api/users/me", apiCfg.rateLimit(authLimit, apiCfg.keyByUser)(apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerDeleteAccount))))
+	mux.Handle("POST /api/users/restore", apiCfg.rateLimit(authLimit, keyByIP)(http.HandlerFunc(apiCfg.handlerRestoreAccount)))
+	mux.Handle("POST /api/users/me/export", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerCreateExport)))
+	mux.Handle("GET /api/users/me/export/{export_id}", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerGetExport)))
+	mux.HandleFunc("GET /api/exports/{export_id}/download", apiCfg.handlerDownloadExport)
+	mux.Handle("GET /api/users/me/preferences", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerGetPreferences)))
+	mux.Handle("PUT /api/users/me/preferences", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerUpdatePreferences)))
+	mux.Handle("PUT /api/users/me/profile", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerUpdateProfile)))
+	mux.Handle("GET /api/users/{handle}", apiCfg.rateLimit(readLimit, apiCfg.keyByUser)(http.HandlerFunc(apiCfg.handlerGetProfile)))
+	mux.Handle("POST /api/chirps", apiCfg.rateLimit(writeLimit, apiCfg.keyByUser)(apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerCreateChip))))
+	mux.Handle("GET /api/chirps", apiCfg.rateLimit(readLimit, apiCfg.keyByUser)(http.HandlerFunc(apiCfg.handlerGetAllChirps)))
+	mux.Handle("GET /api/chirps/{chirp_id}", apiCfg.rateLimit(readLimit, apiCfg.keyByUser)(http.HandlerFunc(apiCfg.handlerGetChirpById)))
+	mux.Handle("POST /api/polls/{poll_id}/vote", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerVotePoll)))
+	mux.Handle("POST /api/drafts", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerCreateDraft)))
+	mux.Handle("GET /api/drafts", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerGetDrafts)))
+	mux.Handle("PUT /api/drafts/{draft_id}", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerUpdateDraft)))
+	mux.Handle("DELETE /api/drafts/{draft_id}", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerDeleteDraft)))
+	mux.Handle("GET /api/scheduled", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerGetScheduled)))
+	mux.Handle("DELETE /api/chirps/{chirp_id}", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerDeleteChirp)))
+	mux.Handle("POST /api/chirps/{chirp_id}/restore", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerRestoreChirp)))
+	mux.Handle("GET /api/trash", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerGetTrash)))
+	mux.Handle("POST /api/chirps/{chirp_id}/bookmark", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerBookmarkChirp)))
+	mux.Handle("DELETE /api/chirps/{chirp_id}/bookmark", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerUnbookmarkChirp)))
+	mux.Handle("POST /api/chirps/{chirp_id}/pin", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerPinChirp)))
+	mux.Handle("DELETE /api/chirps/{chirp_id}/pin", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerUnpinChirp)))
+	mux.Handle("PUT /api/users/me/pins", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerReorderPins)))
+	mux.Handle("GET /api/bookmarks", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerGetBookmarks)))
+	mux.Handle("POST /api/lists", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerCreateList)))
+	mux.Handle("GET /api/lists", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerGetLists)))
+	mux.Handle("GET /api/lists/{list_id}", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerGetList)))
+	mux.Handle("DELETE /api/lists/{list_id}", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerDeleteList)))
+	mux.Handle("POST /api/lists/{list_id}/members", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerAddListMember)))
+	mux.Handle("DELETE /api/lists/{list_id}/members/{user_id}", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerRemoveListMember)))
+	mux.Handle("GET /api/lists/{list_id}/chirps", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerGetListChirps)))
+	mux.Handle("POST /api/reports", apiCfg.rateLimit(writeLimit, apiCfg.keyByUser)(apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerCreateReport))))
+	mux.Handle("POST /api/login", apiCfg.rateLimit(authLimit, keyByIP)(http.HandlerFunc(apiCfg.handlerLogin)))
+	mux.Handle("POST /api/refresh", apiCfg.rateLimit(authLimit, keyByIP)(http.HandlerFunc(apiCfg.handlerRefreshToken)))
+	mux.Handle("POST /api/revoke", apiCfg.rateLimit(authLimit, keyByIP)(http.HandlerFunc(apiCfg.handlerRevokeRefreshToken)))
+	mux.Handle("POST /api/polka/webhooks", apiCfg.rateLimit(webhookLimit, keyByAPIKey)(http.HandlerFunc(apiCfg.handlerUpdateUserRed)))
+	mux.HandleFunc("GET /api/ws", apiCfg.handlerWebSocket)
+	mux.Handle("POST /api/media", apiCfg.rateLimit(writeLimit, apiCfg.keyByUser)(apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerUploadMedia))))
+	if _, ok := blobs.(*blob.LocalStore); ok {
+		mux.HandleFunc("GET /media/{key}", apiCfg.handlerServeMedia)
+	}
+	mux.Handle("GET /api/notifications", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerGetNotifications)))
+	mux.Handle("POST /api/notifications/read", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerMarkAllNotificationsRead)))
+	mux.Handle("POST /api/notifications/{notification_id}/read", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerMarkNotificationRead)))
+	mux.Handle("POST /api/conversations", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerCreateConversation)))
+	mux.Handle("GET /api/conversations", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerGetConversations)))
+	mux.Handle("GET /api/conversations/{conversation_id}", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerGetConversation)))
+	mux.Handle("GET /api/conversations/{conversation_id}/messages", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerGetMessages)))
+	mux.Handle("POST /api/conversations/{conversation_id}/messages", apiCfg.rateLimit(writeLimit, apiCfg.keyByUser)(apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerSendMessage))))
+	mux.Handle("POST /api/conversations/{conversation_id}/read", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerMarkConversationRead)))
+	mux.Handle("POST /api/users/{user_id}/block", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerBlockUser)))
+	mux.Handle("DELETE /api/users/{user_id}/block", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerUnblockUser)))
+	mux.Handle("POST /api/users/{user_id}/mute", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerMuteUser)))
+	mux.Handle("DELETE /api/users/{user_id}/mute", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerUnmuteUser)))
+	mux.Handle("GET /api/blocks", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerGetBlocks)))
+	mux.Handle("GET /api/mutes", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerGetMutes)))
+	mux.Handle("GET /api/notifications/preferences", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerGetNotificationPreferences)))
+	mux.Handle("PUT /api/notifications/preferences", apiCfg.middlewareValidateJWT(http.HandlerFunc(apiCfg.handlerUpdateNotificationPreferences)))
+	handler := apiCfg.metrics.middleware(middlewareTraceRoute(mux))
+	handler = limitBody(serverConfig.MaxBodyBytes, handler)
+	server := newServer(serverConfig, otelhttp.NewHandler(middlewareLog(handler), "http.server"))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	apiCfg.previews.Start(ctx, linkPreviewWorkers)
+	schedulerDone := make(chan struct{})
+	go func() {
+		defer close(schedulerDone)
+		apiCfg.runScheduler(ctx, schedulerInterval)
+	}()
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("Starting server", "addr", serverConfig.Addr, "tls", serverConfig.TLS())
+		serveErr <- listen(serverConfig, server)
+	}()
+	exitCode := 0
+	select {
+	case <-ctx.Done():
+		slog.Info("Shutting down")
+	case err := <-serveErr:
+		// The server never started or stopped by itself; shut the workers
+		// down all the same.
+		slog.Error("Error starting server", "error", err)
+		exitCode = 1
+		stop()
+	}
 
+	// Stop accepting new requests and wait for in-flight ones first, then
+	// drain the websocket connections, which http.Server.Shutdown doesn't
+	// track, and the background workers.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), serverConfig.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Error shutting down server", "error", err)
+	}
+	if err := apiCfg.hub.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Error draining websocket connections", "error", err)
+	}
+	apiCfg.previews.Wait()
+	<-schedulerDone
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("Error flushing traces", "error", err)
+	}
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
 }
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Sources of new chirps.
+const (
+	chirpSourceAPI       = "api"
+	chirpSourceScheduled = "scheduled"
+)
+
+// Outcomes of Polka webhook events.
+const (
+	webhookProcessed = "processed"
+	webhookIgnored   = "ignored"
+	webhookRejected  = "rejected"
+	webhookFailed    = "failed"
+)
+
+// unmatchedRoute labels requests that matched no route, so that arbitrary
+// paths don't each get their own series.
+const unmatchedRoute = "unmatched"
+
+// metrics holds the Prometheus collectors served at /metrics. They live in
+// their own registry rather than the global one so nothing registered by a
+// dependency ends up in the output unexpectedly.
+type metrics struct {
+	registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	chirpsCreated   *prometheus.CounterVec
+	logins          *prometheus.CounterVec
+	webhookEvents   *prometheus.CounterVec
+}
+
+func newMetrics(db *sql.DB) *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "chirpy_http_requests_total",
+			Help: "HTTP requests by route pattern, method and status code.",
+		}, []string{"route", "method", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "chirpy_http_request_duration_seconds",
+			Help:    "Time taken to handle HTTP requests by route pattern, method and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		chirpsCreated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "chirpy_chirps_created_total",
+			Help: "Chirps created, by whether they were posted directly or published from a schedule.",
+		}, []string{"source"}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "chirpy_logins_total",
+			Help: "Login attempts by outcome (success or failure).",
+		}, []string{"outcome"}),
+		webhookEvents: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "chirpy_webhook_events_total",
+			Help: "Polka webhook events by event type and outcome.",
+		}, []string{"event", "outcome"}),
+	}
+	m.registry.MustRegister(
+		m.requests,
+		m.requestDuration,
+		m.chirpsCreated,
+		m.logins,
+		m.webhookEvents,
+		collectors.NewDBStatsCollector(db, "chirpy"),
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// handler serves the metrics in the Prometheus exposition format.
+func (m *metrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// middleware counts and times requests by the route pattern they matched. It
+// must wrap the ServeMux directly: the mux records the pattern on the request
+// it is given, and a middleware in between that copies the request would
+// hide it.
+func (m *metrics) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rw := recordResponse(w)
+		next.ServeHTTP(rw, r)
+		route := r.Pattern
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := strconv.Itoa(rw.statusCode())
+		m.requests.WithLabelValues(route, r.Method, status).Inc()
+		m.requestDuration.WithLabelValues(route, r.Method, status).Observe(time.Since(start).Seconds())
+	})
+}
+
+func (m *metrics) chirpCreated(source string) {
+	m.chirpsCreated.WithLabelValues(source).Inc()
+}
+
+func (m *metrics) login(failed bool) {
+	outcome := auditSuccess
+	if failed {
+		outcome = auditFailure
+	}
+	m.logins.WithLabelValues(outcome).Inc()
+}
+
+// webhookEvent counts a Polka event. Events Chirpy doesn't handle are
+// counted together, since the event name comes from the request.
+func (m *metrics) webhookEvent(event, outcome string) {
+	if event != polkaUserUpgraded {
+		event = "other"
+	}
+	m.webhookEvents.WithLabelValues(event, outcome).Inc()
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kien-tn/chirpy/internal/database"
+	"github.com/kien-tn/chirpy/internal/realtime"
+)
+
+const (
+	notificationMention = "mention"
+	notificationReply   = "reply"
+	notificationLike    = "like"
+	notificationFollow  = "follow"
+	notificationQuote   = "quote"
+	// notificationPollEnded has no actor; it tells an author their poll closed.
+	notificationPollEnded = "poll_ended"
+)
+
+var notificationTypes = []string{
+	notificationMention,
+	notificationReply,
+	notificationLike,
+	notificationFollow,
+	notificationQuote,
+	notificationPollEnded,
+}
+
+var mentionPattern = regexp.MustCompile(`(?:^|[^A-Za-z0-9_])@([A-Za-z0-9_]{1,15})\b`)
+
+type Notification struct {
+	ID          uuid.UUID  `json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	Type        string     `json:"type"`
+	ChirpID     *uuid.UUID `json:"chirp_id,omitempty"`
+	LastActorID *uuid.UUID `json:"last_actor_id,omitempty"`
+	ActorCount  int64      `json:"actor_count"`
+	Summary     string     `json:"summary"`
+	Read        bool       `json:"read"`
+}
+
+// notificationEvent describes something that happened to UserID. Events that
+// share a type and chirp are coalesced into one unread notification.
+type notificationEvent struct {
+	UserID  uuid.UUID
+	ActorID uuid.UUID
+	Type    string
+	ChirpID uuid.UUID
+}
+
+// notify records a notification for the recipient, unless they caused it
+// themselves or have turned that type off, and pushes it to their live
+// connections.
+func (cfg *apiConfig) notify(ctx context.Context, e notificationEvent) error {
+	if e.ActorID == e.UserID {
+		return nil
+	}
+	enabled, err := cfg.db.NotificationEnabled(ctx, database.NotificationEnabledParams{
+		UserID: e.UserID,
+		Type:   e.Type,
+	})
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return nil
+	}
+	groupKey := ""
+	if e.ChirpID != uuid.Nil {
+		groupKey = e.ChirpID.String()
+	}
+	n, err := cfg.db.UpsertNotification(ctx, database.UpsertNotificationParams{
+		UserID:      e.UserID,
+		Type:        e.Type,
+		ChirpID:     uuid.NullUUID{UUID: e.ChirpID, Valid: e.ChirpID != uuid.Nil},
+		GroupKey:    groupKey,
+		LastActorID: uuid.NullUUID{UUID: e.ActorID, Valid: e.ActorID != uuid.Nil},
+	})
+	if err != nil {
+		return err
+	}
+	if e.ActorID != uuid.Nil {
+		err = cfg.db.AddNotificationActor(ctx, database.AddNotificationActorParams{
+			NotificationID: n.ID,
+			ActorID:        e.ActorID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	row, err := cfg.db.GetNotificationByID(ctx, n.ID)
+	if err != nil {
+		return err
+	}
+	cfg.hub.PublishToUser(e.UserID, realtime.TopicNotifications, "notification.created",
+		notificationFromRow(database.ListNotificationsRow(row)))
+	return nil
+}
+
+func notificationFromRow(n database.ListNotificationsRow) Notification {
+	out := Notification{
+		ID:         n.ID,
+		CreatedAt:  n.CreatedAt,
+		UpdatedAt:  n.UpdatedAt,
+		Type:       n.Type,
+		ActorCount: n.ActorCount,
+		Summary:    notificationSummary(n.Type, n.ActorCount),
+		Read:       n.ReadAt.Valid,
+	}
+	if n.ChirpID.Valid {
+		out.ChirpID = &n.ChirpID.UUID
+	}
+	if n.LastActorID.Valid {
+		out.LastActorID = &n.LastActorID.UUID
+	}
+	return out
+}
+
+// notificationSummary renders the coalesced text, e.g. "5 people liked your chirp".
+func notificationSummary(notificationType string, actors int64) string {
+	who := "1 person"
+	if actors != 1 {
+		who = fmt.Sprintf("%d people", actors)
+	}
+	switch notificationType {
+	case notificationMention:
+		return who + " mentioned you"
+	case notificationReply:
+		return who + " replied to your chirp"
+	case notificationLike:
+		return who + " liked your chirp"
+	case notificationFollow:
+		return who + " followed you"
+	case notificationQuote:
+		return who + " quoted your chirp"
+	case notificationPollEnded:
+		return "Your poll has ended"
+	}
+	return "You have a new notification"
+}
+
+func validNotificationType(t string) bool {
+	return slices.Contains(notificationTypes, t)
+}
+
+// notificationPreferences fills in the default (enabled) for every type the
+// user hasn't configured.
+func notificationPreferences(stored []database.NotificationPreference) map[string]bool {
+	prefs := map[string]bool{}
+	for _, t := range notificationTypes {
+		prefs[t] = true
+	}
+	for _, p := range stored {
+		prefs[p.Type] = p.Enabled
+	}
+	return prefs
+}
+
+// notifyMentions notifies every user @mentioned in the chirp, except those who
+// have a block in either direction with its author.
+func (cfg *apiConfig) notifyMentions(ctx context.Context, c database.Chirp) error {
+	handles := []string{}
+	for _, m := range mentionPattern.FindAllStringSubmatch(c.Body, -1) {
+		handle := strings.ToLower(m[1])
+		if !slices.Contains(handles, handle) {
+			handles = append(handles, handle)
+		}
+	}
+	if len(handles) == 0 {
+		return nil
+	}
+	userIDs, err := cfg.db.GetUserIDsByHandles(ctx, handles)
+	if err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		blocked, err := cfg.db.BlockExistsBetween(ctx, database.BlockExistsBetweenParams{
+			UserID:   c.UserID,
+			OtherIds: []uuid.UUID{userID},
+		})
+		if err != nil {
+			return err
+		}
+		if blocked {
+			continue
+		}
+		err = cfg.notify(ctx, notificationEvent{
+			UserID:  userID,
+			ActorID: c.UserID,
+			Type:    notificationMention,
+			ChirpID: c.ID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// pageCursor is an opaque keyset position: the timestamp and ID of the last
+// item on the previous page, encoded for use in a `cursor` query parameter.
+type pageCursor struct {
+	Time time.Time
+	ID   uuid.UUID
+}
+
+// firstPage sorts after every real item, so it selects the newest page when
+// listing in descending order.
+var firstPage = pageCursor{
+	Time: time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC),
+	ID:   uuid.Max,
+}
+
+func encodeCursor(t time.Time, id uuid.UUID) string {
+	raw := t.UTC().Format(time.RFC3339Nano) + "|" + id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string) (pageCursor, error) {
+	if s == "" {
+		return firstPage, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return pageCursor{}, err
+	}
+	ts, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return pageCursor{}, fmt.Errorf("malformed cursor")
+	}
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return pageCursor{}, err
+	}
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return pageCursor{}, err
+	}
+	return pageCursor{Time: t, ID: parsedID}, nil
+}
+
+// pageParams reads the `cursor` and `limit` query parameters.
+func pageParams(r *http.Request) (pageCursor, int32, error) {
+	c, err := decodeCursor(r.URL.Query().Get("cursor"))
+	if err != nil {
+		return pageCursor{}, 0, fmt.Errorf("invalid cursor: %w", err)
+	}
+	limit := defaultPageSize
+	if s := r.URL.Query().Get("limit"); s != "" {
+		limit, err = strconv.Atoi(s)
+		if err != nil || limit < 1 {
+			return pageCursor{}, 0, fmt.Errorf("invalid limit %q", s)
+		}
+		limit = min(limit, maxPageSize)
+	}
+	return c, int32(limit), nil
+}
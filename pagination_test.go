@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 30, 0, 123456789, time.UTC)
+	id := uuid.New()
+	c, err := decodeCursor(encodeCursor(at, id))
+	if err != nil {
+		t.Fatalf("decodeCursor returned error: %v", err)
+	}
+	if !c.Time.Equal(at) || c.ID != id {
+		t.Errorf("decodeCursor = %+v, want %v and %v", c, at, id)
+	}
+
+	if c, err := decodeCursor(""); err != nil || c != firstPage {
+		t.Errorf("decodeCursor(\"\") = %+v, %v; want the first page", c, err)
+	}
+	for _, s := range []string{"not base64!", "bm8tc2VwYXJhdG9y", "YmFkLXRpbWV8eA"} {
+		if _, err := decodeCursor(s); err == nil {
+			t.Errorf("decodeCursor(%q) accepted a malformed cursor", s)
+		}
+	}
+}
+
+func TestPageParams(t *testing.T) {
+	tests := []struct {
+		query     string
+		wantLimit int32
+		wantErr   bool
+	}{
+		{"", defaultPageSize, false},
+		{"limit=5", 5, false},
+		{"limit=1000", maxPageSize, false},
+		{"limit=0", 0, true},
+		{"limit=ten", 0, true},
+		{"cursor=garbage", 0, true},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/api/bookmarks?"+tt.query, nil)
+		c, limit, err := pageParams(r)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("pageParams(%q) returned error %v, wantErr %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if err == nil && (limit != tt.wantLimit || c != firstPage) {
+			t.Errorf("pageParams(%q) = %+v, %d; want the first page and %d", tt.query, c, limit, tt.wantLimit)
+		}
+	}
+}
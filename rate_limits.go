@@ -0,0 +1,51 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kien-tn/chirpy/internal/auth"
+	"github.com/kien-tn/chirpy/internal/ratelimit"
+)
+
+// Rate limit policies. Signing in and creating accounts are limited tightly
+// per IP address to slow down password guessing and mass sign-ups; reads are
+// generous.
+var (
+	authLimit    = ratelimit.Policy{Name: "auth", Limit: 10, Period: time.Minute}
+	signupLimit  = ratelimit.Policy{Name: "signup", Limit: 5, Period: time.Hour}
+	writeLimit   = ratelimit.Policy{Name: "write", Limit: 60, Period: time.Minute}
+	readLimit    = ratelimit.Policy{Name: "read", Limit: 600, Period: time.Minute}
+	webhookLimit = ratelimit.Policy{Name: "webhook", Limit: 120, Period: time.Minute}
+)
+
+// rateLimit returns middleware limiting requests to p, one bucket per key.
+func (cfg *apiConfig) rateLimit(p ratelimit.Policy, key ratelimit.KeyFunc) func(http.Handler) http.Handler {
+	return cfg.limiter.Middleware(p, key)
+}
+
+func keyByIP(r *http.Request) string {
+	return "ip:" + clientIP(r)
+}
+
+// keyByUser keys signed-in requests by user and the rest by IP address.
+func (cfg *apiConfig) keyByUser(r *http.Request) string {
+	if userID := cfg.viewerID(r); userID != uuid.Nil {
+		return "user:" + userID.String()
+	}
+	return keyByIP(r)
+}
+
+// keyByAPIKey keys requests by a hash of their API key, falling back to the
+// IP address.
+func keyByAPIKey(r *http.Request) string {
+	key, err := auth.GetAPIKey(r.Header)
+	if err != nil {
+		return keyByIP(r)
+	}
+	sum := sha256.Sum256([]byte(key))
+	return "key:" + hex.EncodeToString(sum[:8])
+}
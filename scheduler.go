@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/kien-tn/chirpy/internal/database"
+)
+
+const (
+	schedulerInterval  = 10 * time.Second
+	scheduledBatchSize = 100
+)
+
+// schedulerJob is a periodic background task. run returns how many items it
+// processed.
+type schedulerJob struct {
+	name string
+	run  func(context.Context) (int, error)
+}
+
+func (cfg *apiConfig) schedulerJobs() []schedulerJob {
+	return []schedulerJob{
+		{name: "scheduled chirps", run: cfg.publishDueDrafts},
+		{name: "delayed chirps", run: cfg.releaseDelayedChirps},
+		{name: "ended polls", run: cfg.closeEndedPolls},
+		{name: "expired trash items", run: cfg.purgeTrash},
+		{name: "data exports", run: cfg.processDataExports},
+		{name: "expired data exports", run: cfg.expireDataExports},
+	}
+}
+
+// runScheduler runs the periodic background jobs until ctx is cancelled.
+func (cfg *apiConfig) runScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, job := range cfg.schedulerJobs() {
+				n, err := job.run(ctx)
+				if err != nil {
+					slog.Error("Error running scheduled job", "job", job.name, "error", err)
+				}
+				if n > 0 {
+					slog.Info("Ran scheduled job", "job", job.name, "processed", n)
+				}
+			}
+		}
+	}
+}
+
+// publishDueDrafts publishes scheduled chirps whose time has come, one
+// transaction per chirp. Each draft is locked with SKIP LOCKED and deleted in
+// the transaction that publishes it, so running several server instances
+// never publishes a chirp twice.
+func (cfg *apiConfig) publishDueDrafts(ctx context.Context) (int, error) {
+	processed := 0
+	for processed < scheduledBatchSize {
+		ok, err := cfg.publishNextDraft(ctx)
+		if err != nil || !ok {
+			return processed, err
+		}
+		processed++
+	}
+	return processed, nil
+}
+
+// publishNextDraft publishes the earliest due draft that no other instance
+// holds, reporting whether there was one.
+func (cfg *apiConfig) publishNextDraft(ctx context.Context) (bool, error) {
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTracedTx(tx)
+	d, err := qtx.LockDueDraft(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// The author may have been suspended or limited since scheduling.
+	u, err := qtx.GetUserByID(ctx, d.UserID)
+	if err != nil {
+		return false, err
+	}
+	state := accountState(u)
+	if state == accountSuspended {
+		return unscheduleDraft(ctx, tx, qtx, d, "account suspended")
+	}
+	exceeded, err := chirpQuotaExceeded(ctx, qtx, d.UserID, state)
+	if err != nil {
+		return false, err
+	}
+	if exceeded {
+		return unscheduleDraft(ctx, tx, qtx, d, "daily chirp quota used up")
+	}
+
+	// The media may have been used by another chirp since the draft was
+	// scheduled. Hand the draft back to its author rather than publishing
+	// it without them.
+	if len(d.MediaIds) > 0 {
+		n, err := qtx.CountAttachableMedia(ctx, database.CountAttachableMediaParams{
+			Ids:    d.MediaIds,
+			UserID: d.UserID,
+		})
+		if err != nil {
+			return false, err
+		}
+		if n != int64(len(d.MediaIds)) {
+			return unscheduleDraft(ctx, tx, qtx, d, "unavailable media")
+		}
+	}
+	if d.QuotedChirpID.Valid {
+		ok, err := cfg.canQuote(ctx, d.UserID, d.QuotedChirpID.UUID)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return unscheduleDraft(ctx, tx, qtx, d, "quoted chirp unavailable")
+		}
+	}
+	// A poll that closed while the chirp waited would never take a vote.
+	if len(d.PollOptions) > 0 && !d.PollClosesAt.Time.After(time.Now()) {
+		return unscheduleDraft(ctx, tx, qtx, d, "poll already closed")
+	}
+
+	n := newChirp{
+		UserID:         d.UserID,
+		Body:           d.Body,
+		MediaIDs:       d.MediaIds,
+		QuotedChirpID:  d.QuotedChirpID,
+		ContentWarning: d.ContentWarning,
+		SensitiveMedia: d.SensitiveMedia,
+	}
+	if _, err := cfg.screenChirp(ctx, &n, true); err != nil {
+		return false, err
+	}
+	c, links, err := insertChirp(ctx, qtx, n)
+	if err != nil {
+		return false, err
+	}
+	if len(d.PollOptions) > 0 {
+		p := pollParams{Options: d.PollOptions, ClosesAt: d.PollClosesAt.Time}
+		if err := insertPoll(ctx, qtx, c.ID, p); err != nil {
+			return false, err
+		}
+	}
+	if _, err := qtx.DeleteDraft(ctx, database.DeleteDraftParams{ID: d.ID, UserID: d.UserID}); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	cfg.metrics.chirpCreated(chirpSourceScheduled)
+	if c.Hold != "" {
+		return true, nil
+	}
+	if _, err := cfg.announceChirp(ctx, c, links); err != nil {
+		slog.Error("Error announcing scheduled chirp", "chirp_id", c.ID, "error", err)
+	}
+	return true, nil
+}
+
+// unscheduleDraft hands a scheduled chirp that can no longer be published
+// back to its author as a draft.
+func unscheduleDraft(ctx context.Context, tx *sql.Tx, qtx *database.Queries, d database.ChirpDraft, reason string) (bool, error) {
+	slog.Warn("Moving scheduled chirp back to drafts", "draft_id", d.ID, "reason", reason)
+	if err := qtx.UnscheduleDraft(ctx, d.ID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+	"time"
+
+	"github.com/kien-tn/chirpy/internal/config"
+)
+
+func newServer(c config.Server, handler http.Handler) *http.Server {
+	s := &http.Server{
+		Addr:              c.Addr,
+		Handler:           handler,
+		ReadTimeout:       c.ReadTimeout,
+		ReadHeaderTimeout: c.ReadHeaderTimeout,
+		WriteTimeout:      c.WriteTimeout,
+		IdleTimeout:       c.IdleTimeout,
+		MaxHeaderBytes:    c.MaxHeaderBytes,
+	}
+	if c.TLS() {
+		s.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return s
+}
+
+// listen serves until the server is shut down, returning
+// http.ErrServerClosed then.
+func listen(c config.Server, s *http.Server) error {
+	if c.TLS() {
+		return s.ListenAndServeTLS(c.TLSCertFile, c.TLSKeyFile)
+	}
+	return s.ListenAndServe()
+}
+
+// bodyLimitExempt lists the paths whose handlers enforce a larger body limit
+// themselves.
+var bodyLimitExempt = map[string]bool{
+	"/api/media": true,
+}
+
+// limitBody rejects request bodies over n bytes. Handlers see the limit as an
+// *http.MaxBytesError when reading the body.
+func limitBody(n int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if bodyLimitExempt[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if r.ContentLength > n {
+			respondWithError(w, http.StatusRequestEntityTooLarge, "Request body is too large", nil)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, n)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// extendWriteDeadline lifts the server's write timeout for a response that
+// may legitimately take longer, such as a large download.
+func extendWriteDeadline(w http.ResponseWriter) {
+	// Writers that don't support deadlines have none to lift.
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+}
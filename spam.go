@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kien-tn/chirpy/internal/database"
+	"github.com/kien-tn/chirpy/internal/spam"
+)
+
+// Holds placed on chirps by the spam filter.
+const (
+	holdDelay  = "delay"
+	holdReview = "review"
+)
+
+// screenChirp scores a new chirp and records the outcome on n: the body hash
+// and, for a delay or queue verdict, the hold. A scheduled chirp has no one
+// to hear about a rejection, so it goes to review instead.
+func (cfg *apiConfig) screenChirp(ctx context.Context, n *newChirp, scheduled bool) (spam.Verdict, error) {
+	author, err := cfg.db.GetUserByID(ctx, n.UserID)
+	if err != nil {
+		return spam.Verdict{}, err
+	}
+	now := time.Now()
+	n.BodyHash = spam.Hash(n.Body)
+	duplicates := int64(0)
+	if n.BodyHash != "" {
+		duplicates, err = cfg.db.CountDuplicateChirps(ctx, database.CountDuplicateChirpsParams{
+			UserID:    n.UserID,
+			BodyHash:  n.BodyHash,
+			CreatedAt: now.Add(-cfg.spam.DuplicateWindow),
+		})
+		if err != nil {
+			return spam.Verdict{}, err
+		}
+	}
+	recent, err := cfg.db.CountChirpsSince(ctx, database.CountChirpsSinceParams{
+		UserID:    n.UserID,
+		CreatedAt: now.Add(-cfg.spam.BurstWindow),
+	})
+	if err != nil {
+		return spam.Verdict{}, err
+	}
+	v := cfg.spam.Score(spam.Signals{
+		Body:         n.Body,
+		AccountAge:   now.Sub(author.CreatedAt),
+		Duplicates:   int(duplicates),
+		RecentChirps: int(recent),
+	})
+	n.Spam = v
+	switch {
+	case v.Action == spam.Delay:
+		n.Hold = holdDelay
+		n.HeldUntil = sql.NullTime{Time: now.Add(cfg.spam.DelayFor).UTC(), Valid: true}
+	case v.Action == spam.Queue, v.Action == spam.Reject && scheduled:
+		n.Hold = holdReview
+	}
+	return v, nil
+}
+
+// queueForReview files a spam report for a chirp held for review, so it shows
+// up in the moderation queue. Dismissing this report, and only this one,
+// publishes the chirp.
+func queueForReview(ctx context.Context, qtx *database.Queries, c database.Chirp, v spam.Verdict) error {
+	_, err := qtx.CreateReport(ctx, database.CreateReportParams{
+		TargetType: reportTargetChirp,
+		ChirpID:    uuid.NullUUID{UUID: c.ID, Valid: true},
+		UserID:     uuid.NullUUID{UUID: c.UserID, Valid: true},
+		ChirpBody:  c.Body,
+		Reason:     "spam",
+		Details:    fmt.Sprintf("Held by the spam filter (score %d: %s)", v.Score, strings.Join(v.Reasons, ", ")),
+		Source:     reportSourceSpamFilter,
+	})
+	return err
+}
+
+// announceReleasedChirp announces a chirp that was just released from a hold.
+func (cfg *apiConfig) announceReleasedChirp(ctx context.Context, c database.Chirp) error {
+	links, err := cfg.db.GetChirpLinks(ctx, c.ID)
+	if err != nil {
+		return err
+	}
+	_, err = cfg.announceChirp(ctx, c, links)
+	return err
+}
+
+// releaseDelayedChirps publishes delayed chirps whose hold has passed.
+func (cfg *apiConfig) releaseDelayedChirps(ctx context.Context) (int, error) {
+	processed := 0
+	for processed < scheduledBatchSize {
+		ok, err := cfg.releaseNextDelayedChirp(ctx)
+		if err != nil || !ok {
+			return processed, err
+		}
+		processed++
+	}
+	return processed, nil
+}
+
+// releaseNextDelayedChirp releases the earliest due delayed chirp that no
+// other instance holds, reporting whether there was one.
+func (cfg *apiConfig) releaseNextDelayedChirp(ctx context.Context) (bool, error) {
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTracedTx(tx)
+	held, err := qtx.LockDueHeldChirp(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	c, err := qtx.ReleaseChirp(ctx, held.ID)
+	if err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	if err := cfg.announceReleasedChirp(ctx, c); err != nil {
+		slog.Error("Error announcing delayed chirp", "chirp_id", c.ID, "error", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,27 @@
+-- name: CreateAuditEntry :exec
+INSERT INTO audit_log (id, created_at, action, outcome, actor_id, target_type, target_id, ip, user_agent, request_id, details)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9
+);
+
+-- name: ListAuditEntries :many
+SELECT * FROM audit_log
+WHERE (sqlc.arg(action)::text = '' OR action = sqlc.arg(action))
+    AND (sqlc.arg(outcome)::text = '' OR outcome = sqlc.arg(outcome))
+    AND (sqlc.arg(actor_id)::uuid = '00000000-0000-0000-0000-000000000000' OR actor_id = sqlc.arg(actor_id))
+    AND (sqlc.arg(target_id)::uuid = '00000000-0000-0000-0000-000000000000' OR target_id = sqlc.arg(target_id))
+    AND created_at >= sqlc.arg(since)::timestamp
+    AND created_at < sqlc.arg(until)::timestamp
+    AND (created_at, id) < (sqlc.arg(cursor_time)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size);
\ No newline at end of file
@@ -0,0 +1,40 @@
+-- name: CreateBlock :exec
+INSERT INTO blocks (blocker_id, blocked_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteBlock :execrows
+DELETE FROM blocks WHERE blocker_id = $1 AND blocked_id = $2;
+
+-- name: GetBlocksByBlocker :many
+SELECT * FROM blocks WHERE blocker_id = $1 ORDER BY created_at DESC;
+
+-- name: GetBlockerIDs :many
+SELECT blocker_id FROM blocks WHERE blocked_id = $1;
+
+-- name: GetBlockedIDs :many
+SELECT blocked_id FROM blocks WHERE blocker_id = $1;
+
+-- name: BlockExistsBetween :one
+SELECT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocker_id = sqlc.arg(user_id) AND blocked_id = ANY(sqlc.arg(other_ids)::uuid[]))
+        OR (blocked_id = sqlc.arg(user_id) AND blocker_id = ANY(sqlc.arg(other_ids)::uuid[]))
+);
+
+-- name: CreateMute :exec
+INSERT INTO mutes (muter_id, muted_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteMute :execrows
+DELETE FROM mutes WHERE muter_id = $1 AND muted_id = $2;
+
+-- name: GetMutesByMuter :many
+SELECT * FROM mutes WHERE muter_id = $1 ORDER BY created_at DESC;
+
+-- name: GetMutedIDs :many
+SELECT muted_id FROM mutes WHERE muter_id = $1;
+
+-- name: GetMuterIDs :many
+SELECT muter_id FROM mutes WHERE muted_id = $1;
@@ -0,0 +1,25 @@
+-- name: CreateBookmark :exec
+INSERT INTO bookmarks (user_id, chirp_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteBookmark :execrows
+DELETE FROM bookmarks WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: ListBookmarkedChirps :many
+SELECT c.*, b.created_at AS bookmarked_at
+FROM bookmarks b
+JOIN chirps c ON c.id = b.chirp_id
+JOIN users u ON u.id = c.user_id
+WHERE b.user_id = sqlc.arg(user_id)
+    AND c.deleted_at IS NULL AND u.deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT 1 FROM blocks bl WHERE bl.blocker_id = c.user_id AND bl.blocked_id = sqlc.arg(user_id)
+    )
+    AND (u.account_state <> 'shadowbanned'
+        OR u.account_state_expires_at <= (NOW() AT TIME ZONE 'UTC')
+        OR c.user_id = sqlc.arg(user_id))
+    AND (c.hold = '' OR c.user_id = sqlc.arg(user_id))
+    AND (b.created_at, b.chirp_id) < (sqlc.arg(cursor_time)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY b.created_at DESC, b.chirp_id DESC
+LIMIT sqlc.arg(page_size);
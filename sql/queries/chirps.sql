@@ -1,11 +1,17 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, quoted_chirp_id, content_warning, sensitive_media, body_hash, hold, held_until)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
 )
 RETURNING *;
 
@@ -13,13 +19,157 @@ RETURNING *;
 DELETE FROM chirps;
 
 -- name: GetAllChirps :many
-SELECT * FROM chirps ORDER BY created_at ASC;
+SELECT c.* FROM chirps c
+JOIN users u ON u.id = c.user_id
+WHERE c.deleted_at IS NULL AND u.deleted_at IS NULL
+    AND (u.account_state <> 'shadowbanned'
+        OR u.account_state_expires_at <= (NOW() AT TIME ZONE 'UTC')
+        OR c.user_id = sqlc.arg(viewer_id))
+    AND (c.hold = '' OR c.user_id = sqlc.arg(viewer_id))
+ORDER BY c.created_at ASC;
+
+-- name: ListChirps :many
+SELECT c.* FROM chirps c
+JOIN users u ON u.id = c.user_id
+WHERE c.deleted_at IS NULL AND u.deleted_at IS NULL
+    AND (sqlc.arg(author_id)::uuid = '00000000-0000-0000-0000-000000000000' OR c.user_id = sqlc.arg(author_id))
+    AND NOT EXISTS (
+        SELECT 1 FROM blocks bl WHERE bl.blocker_id = c.user_id AND bl.blocked_id = sqlc.arg(viewer_id)
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM mutes mu WHERE mu.muter_id = sqlc.arg(viewer_id) AND mu.muted_id = c.user_id
+    )
+    AND (u.account_state <> 'shadowbanned'
+        OR u.account_state_expires_at <= (NOW() AT TIME ZONE 'UTC')
+        OR c.user_id = sqlc.arg(viewer_id))
+    AND (c.hold = '' OR c.user_id = sqlc.arg(viewer_id))
+    AND (sqlc.arg(author_id)::uuid = '00000000-0000-0000-0000-000000000000' OR NOT EXISTS (
+        SELECT 1 FROM pinned_chirps p WHERE p.user_id = c.user_id AND p.chirp_id = c.id
+    ))
+    AND (c.created_at, c.id) < (sqlc.arg(cursor_time)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY c.created_at DESC, c.id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: ListPinnedChirps :many
+SELECT c.* FROM chirps c
+JOIN pinned_chirps p ON p.user_id = c.user_id AND p.chirp_id = c.id
+JOIN users u ON u.id = c.user_id
+WHERE c.user_id = sqlc.arg(author_id) AND c.deleted_at IS NULL AND u.deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT 1 FROM blocks bl WHERE bl.blocker_id = c.user_id AND bl.blocked_id = sqlc.arg(viewer_id)
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM mutes mu WHERE mu.muter_id = sqlc.arg(viewer_id) AND mu.muted_id = c.user_id
+    )
+    AND (u.account_state <> 'shadowbanned'
+        OR u.account_state_expires_at <= (NOW() AT TIME ZONE 'UTC')
+        OR c.user_id = sqlc.arg(viewer_id))
+    AND (c.hold = '' OR c.user_id = sqlc.arg(viewer_id))
+ORDER BY p.position ASC;
 
 -- name: GetChirpByID :one
-SELECT * FROM chirps WHERE id = $1;
+SELECT c.* FROM chirps c
+JOIN users u ON u.id = c.user_id
+WHERE c.id = $1 AND c.deleted_at IS NULL AND u.deleted_at IS NULL;
+
+-- name: GetVisibleChirpByID :one
+SELECT c.* FROM chirps c
+JOIN users u ON u.id = c.user_id
+WHERE c.id = sqlc.arg(id) AND c.deleted_at IS NULL AND u.deleted_at IS NULL
+    AND (u.account_state <> 'shadowbanned'
+        OR u.account_state_expires_at <= (NOW() AT TIME ZONE 'UTC')
+        OR c.user_id = sqlc.arg(viewer_id))
+    AND (c.hold = '' OR c.user_id = sqlc.arg(viewer_id));
+
+-- name: GetChirpsByIDs :many
+SELECT c.* FROM chirps c
+JOIN users u ON u.id = c.user_id
+WHERE c.id = ANY(sqlc.arg(ids)::uuid[]) AND c.deleted_at IS NULL AND u.deleted_at IS NULL
+    AND (u.account_state <> 'shadowbanned'
+        OR u.account_state_expires_at <= (NOW() AT TIME ZONE 'UTC')
+        OR c.user_id = sqlc.arg(viewer_id))
+    AND (c.hold = '' OR c.user_id = sqlc.arg(viewer_id));
+
+-- name: SetChirpLabels :one
+UPDATE chirps
+SET content_warning = $2, sensitive_media = $3, updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: RemoveChirp :execrows
+UPDATE chirps
+SET deleted_at = COALESCE(deleted_at, NOW()), removed_at = NOW()
+WHERE id = $1 AND removed_at IS NULL;
 
 -- name: DeleteChirpByID :exec
-DELETE FROM chirps WHERE id = $1 AND user_id = $2;
+UPDATE chirps
+SET deleted_at = NOW()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
 
 -- name: GetChirpsByUserID :many
-SELECT * FROM chirps WHERE user_id = $1 ORDER BY created_at ASC;
\ No newline at end of file
+SELECT c.* FROM chirps c
+JOIN users u ON u.id = c.user_id
+WHERE c.user_id = sqlc.arg(user_id) AND c.deleted_at IS NULL AND u.deleted_at IS NULL
+    AND (u.account_state <> 'shadowbanned'
+        OR u.account_state_expires_at <= (NOW() AT TIME ZONE 'UTC')
+        OR c.user_id = sqlc.arg(viewer_id))
+    AND (c.hold = '' OR c.user_id = sqlc.arg(viewer_id))
+ORDER BY c.created_at ASC;
+
+-- name: CountChirpsByUserID :one
+SELECT COUNT(*) FROM chirps c
+JOIN users u ON u.id = c.user_id
+WHERE c.user_id = sqlc.arg(user_id) AND c.deleted_at IS NULL AND u.deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT 1 FROM blocks bl WHERE bl.blocker_id = c.user_id AND bl.blocked_id = sqlc.arg(viewer_id)
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM mutes mu WHERE mu.muter_id = sqlc.arg(viewer_id) AND mu.muted_id = c.user_id
+    )
+    AND (u.account_state <> 'shadowbanned'
+        OR u.account_state_expires_at <= (NOW() AT TIME ZONE 'UTC')
+        OR c.user_id = sqlc.arg(viewer_id))
+    AND (c.hold = '' OR c.user_id = sqlc.arg(viewer_id));
+
+-- name: GetDeletedChirpsByUserID :many
+SELECT * FROM chirps
+WHERE user_id = sqlc.arg(user_id) AND removed_at IS NULL
+  AND deleted_at > NOW() - make_interval(days => sqlc.arg(retention_days)::int)
+ORDER BY deleted_at DESC;
+
+-- name: RestoreChirp :one
+UPDATE chirps
+SET deleted_at = NULL
+WHERE id = sqlc.arg(id) AND user_id = sqlc.arg(user_id) AND removed_at IS NULL
+  AND deleted_at > NOW() - make_interval(days => sqlc.arg(retention_days)::int)
+RETURNING *;
+
+-- name: PurgeDeletedChirps :many
+WITH purged AS (
+    DELETE FROM chirps
+    WHERE deleted_at <= NOW() - make_interval(days => sqlc.arg(retention_days)::int)
+    RETURNING id
+)
+SELECT purged.id, m.storage_key, m.thumbnail_key
+FROM purged
+LEFT JOIN media_attachments m ON m.chirp_id = purged.id;
+
+-- name: CountChirpsSince :one
+SELECT COUNT(*) FROM chirps WHERE user_id = $1 AND created_at > $2;
+
+-- name: CountDuplicateChirps :one
+SELECT COUNT(*) FROM chirps WHERE user_id = $1 AND body_hash = $2 AND created_at > $3;
+
+-- name: LockDueHeldChirp :one
+SELECT * FROM chirps
+WHERE hold = 'delay' AND held_until <= (NOW() AT TIME ZONE 'UTC') AND deleted_at IS NULL
+  AND user_id NOT IN (SELECT id FROM users WHERE deleted_at IS NOT NULL)
+ORDER BY held_until ASC
+LIMIT 1
+FOR UPDATE SKIP LOCKED;
+
+-- name: ReleaseChirp :one
+UPDATE chirps
+SET hold = '', held_until = NULL, created_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND hold <> '' AND deleted_at IS NULL
+RETURNING *;
\ No newline at end of file
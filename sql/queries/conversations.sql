@@ -0,0 +1,71 @@
+-- name: CreateConversation :one
+INSERT INTO conversations (id, created_at, updated_at, created_by, is_group, direct_key)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3
+)
+ON CONFLICT (direct_key)
+DO UPDATE SET updated_at = conversations.updated_at
+RETURNING *;
+
+-- name: AddConversationParticipant :exec
+INSERT INTO conversation_participants (conversation_id, user_id, joined_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: GetConversationByID :one
+SELECT * FROM conversations WHERE id = $1;
+
+-- name: GetConversationParticipants :many
+SELECT * FROM conversation_participants WHERE conversation_id = $1 ORDER BY joined_at ASC, user_id ASC;
+
+-- name: IsConversationParticipant :one
+SELECT EXISTS (
+    SELECT 1 FROM conversation_participants WHERE conversation_id = $1 AND user_id = $2
+);
+
+-- name: ListConversationsForUser :many
+SELECT c.*, p.last_read_at,
+    (
+        SELECT COUNT(*) FROM messages m
+        WHERE m.conversation_id = c.id
+            AND m.sender_id <> p.user_id
+            AND (p.last_read_at IS NULL OR m.created_at > p.last_read_at)
+    ) AS unread_count
+FROM conversations c
+JOIN conversation_participants p ON p.conversation_id = c.id
+WHERE p.user_id = sqlc.arg(user_id)
+    AND (c.updated_at, c.id) < (sqlc.arg(cursor_time)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY c.updated_at DESC, c.id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: TouchConversation :exec
+UPDATE conversations SET updated_at = NOW() WHERE id = $1;
+
+-- name: CreateMessage :one
+INSERT INTO messages (id, created_at, conversation_id, sender_id, body)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3
+)
+RETURNING *;
+
+-- name: ListMessages :many
+SELECT * FROM messages
+WHERE conversation_id = sqlc.arg(conversation_id)
+    AND (created_at, id) < (sqlc.arg(cursor_time)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: MarkConversationRead :one
+UPDATE conversation_participants
+SET last_read_at = NOW()
+WHERE conversation_id = $1 AND user_id = $2
+RETURNING *;
@@ -0,0 +1,56 @@
+-- name: CreateDraft :one
+INSERT INTO chirp_drafts (id, created_at, updated_at, user_id, body, media_ids, publish_at, poll_options, poll_closes_at, quoted_chirp_id,
+    content_warning, sensitive_media)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9
+)
+RETURNING *;
+
+-- name: GetDraftsByUserID :many
+SELECT * FROM chirp_drafts
+WHERE user_id = $1 AND publish_at IS NULL
+ORDER BY updated_at DESC;
+
+-- name: GetScheduledByUserID :many
+SELECT * FROM chirp_drafts
+WHERE user_id = $1 AND publish_at IS NOT NULL
+ORDER BY publish_at ASC;
+
+-- name: UpdateDraft :one
+UPDATE chirp_drafts
+SET body = $3,
+    media_ids = $4,
+    publish_at = $5,
+    poll_options = $6,
+    poll_closes_at = $7,
+    quoted_chirp_id = $8,
+    content_warning = $9,
+    sensitive_media = $10,
+    updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteDraft :execrows
+DELETE FROM chirp_drafts WHERE id = $1 AND user_id = $2;
+
+-- name: LockDueDraft :one
+SELECT * FROM chirp_drafts
+WHERE publish_at <= (NOW() AT TIME ZONE 'UTC')
+  AND user_id NOT IN (SELECT id FROM users WHERE deleted_at IS NOT NULL)
+ORDER BY publish_at ASC
+LIMIT 1
+FOR UPDATE SKIP LOCKED;
+
+-- name: UnscheduleDraft :exec
+UPDATE chirp_drafts SET publish_at = NULL, updated_at = NOW() WHERE id = $1;
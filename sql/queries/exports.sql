@@ -0,0 +1,55 @@
+-- name: CreateDataExport :one
+INSERT INTO data_exports (id, created_at, updated_at, user_id)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1)
+RETURNING *;
+
+-- name: GetDataExport :one
+SELECT * FROM data_exports WHERE id = $1 AND user_id = $2;
+
+-- name: GetDataExportByID :one
+SELECT * FROM data_exports WHERE id = $1;
+
+-- name: ClaimDataExport :one
+UPDATE data_exports
+SET status = 'processing',
+    updated_at = NOW()
+WHERE id = (
+    SELECT id FROM data_exports
+    WHERE status = 'pending'
+       OR (status = 'processing' AND updated_at < NOW() - INTERVAL '1 hour')
+    ORDER BY created_at ASC
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CompleteDataExport :exec
+UPDATE data_exports
+SET status = 'ready',
+    storage_key = $2,
+    size_bytes = $3,
+    expires_at = $4,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: FailDataExport :exec
+UPDATE data_exports
+SET status = 'failed',
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteExpiredDataExports :many
+DELETE FROM data_exports
+WHERE expires_at <= (NOW() AT TIME ZONE 'UTC')
+RETURNING storage_key;
+
+-- name: GetChirpsForExport :many
+SELECT * FROM chirps WHERE user_id = $1 ORDER BY created_at ASC;
+
+-- name: GetMediaByUserID :many
+SELECT * FROM media_attachments WHERE user_id = $1 ORDER BY created_at ASC;
+
+-- name: GetSessionsByUserID :many
+SELECT created_at, expired_at, revoked_at FROM refresh_tokens
+WHERE user_id = $1
+ORDER BY created_at ASC;
@@ -0,0 +1,31 @@
+-- name: AddChirpLink :exec
+INSERT INTO chirp_links (chirp_id, url, position)
+VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING;
+
+-- name: LinkPreviewIsFresh :one
+SELECT EXISTS (
+    SELECT 1 FROM link_previews
+    WHERE url = $1 AND fetched_at > NOW() - INTERVAL '1 day'
+);
+
+-- name: UpsertLinkPreview :exec
+INSERT INTO link_previews (url, fetched_at, ok, title, description, image_url, site_name)
+VALUES ($1, NOW(), $2, $3, $4, $5, $6)
+ON CONFLICT (url) DO UPDATE
+SET fetched_at = NOW(),
+    ok = EXCLUDED.ok,
+    title = EXCLUDED.title,
+    description = EXCLUDED.description,
+    image_url = EXCLUDED.image_url,
+    site_name = EXCLUDED.site_name;
+
+-- name: GetLinkPreviewsByChirpIDs :many
+SELECT cl.chirp_id, lp.url, lp.title, lp.description, lp.image_url, lp.site_name
+FROM chirp_links cl
+JOIN link_previews lp ON lp.url = cl.url
+WHERE cl.chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]) AND lp.ok
+ORDER BY cl.chirp_id, cl.position ASC;
+
+-- name: GetChirpLinks :many
+SELECT url FROM chirp_links WHERE chirp_id = $1 ORDER BY position ASC;
\ No newline at end of file
@@ -0,0 +1,62 @@
+-- name: CreateList :one
+INSERT INTO lists (id, created_at, updated_at, user_id, name, description)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3
+)
+RETURNING *;
+
+-- name: GetListByID :one
+SELECT * FROM lists WHERE id = $1 AND user_id = $2;
+
+-- name: GetListsByUserID :many
+SELECT l.*,
+    (SELECT COUNT(*) FROM list_members m WHERE m.list_id = l.id) AS member_count
+FROM lists l
+WHERE l.user_id = $1
+ORDER BY l.created_at ASC;
+
+-- name: DeleteList :execrows
+DELETE FROM lists WHERE id = $1 AND user_id = $2;
+
+-- name: AddListMember :exec
+INSERT INTO list_members (list_id, user_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveListMember :execrows
+DELETE FROM list_members WHERE list_id = $1 AND user_id = $2;
+
+-- name: CountListMembers :one
+SELECT COUNT(*) FROM list_members WHERE list_id = $1;
+
+-- name: GetListMembers :many
+SELECT u.id, u.handle, u.display_name, m.created_at
+FROM list_members m
+JOIN users u ON u.id = m.user_id
+WHERE m.list_id = $1 AND u.deleted_at IS NULL
+ORDER BY m.created_at ASC;
+
+-- name: ListListChirps :many
+SELECT c.* FROM chirps c
+JOIN list_members m ON m.user_id = c.user_id
+JOIN users u ON u.id = c.user_id
+WHERE m.list_id = sqlc.arg(list_id)
+    AND c.deleted_at IS NULL AND u.deleted_at IS NULL
+    AND NOT EXISTS (
+        SELECT 1 FROM blocks bl WHERE bl.blocker_id = c.user_id AND bl.blocked_id = sqlc.arg(viewer_id)
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM mutes mu WHERE mu.muter_id = sqlc.arg(viewer_id) AND mu.muted_id = c.user_id
+    )
+    AND (u.account_state <> 'shadowbanned'
+        OR u.account_state_expires_at <= (NOW() AT TIME ZONE 'UTC')
+        OR c.user_id = sqlc.arg(viewer_id))
+    AND (c.hold = '' OR c.user_id = sqlc.arg(viewer_id))
+    AND (c.created_at, c.id) < (sqlc.arg(cursor_time)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY c.created_at DESC, c.id DESC
+LIMIT sqlc.arg(page_size);
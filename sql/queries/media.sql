@@ -0,0 +1,29 @@
+-- name: CreateMediaAttachment :one
+INSERT INTO media_attachments (id, created_at, user_id, content_type, storage_key, thumbnail_key, width, height, size_bytes)
+VALUES (
+    $1,
+    NOW(),
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
+)
+RETURNING *;
+
+-- name: AttachMediaToChirp :execrows
+UPDATE media_attachments
+SET chirp_id = $1,
+    position = $2
+WHERE id = $3 AND user_id = $4 AND chirp_id IS NULL;
+
+-- name: GetMediaByChirpIDs :many
+SELECT * FROM media_attachments
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+ORDER BY chirp_id, position ASC;
+
+-- name: CountAttachableMedia :one
+SELECT COUNT(*) FROM media_attachments
+WHERE id = ANY(sqlc.arg(ids)::uuid[]) AND user_id = sqlc.arg(user_id) AND chirp_id IS NULL;
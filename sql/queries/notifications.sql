@@ -0,0 +1,67 @@
+-- name: UpsertNotification :one
+INSERT INTO notifications (id, created_at, updated_at, user_id, type, chirp_id, group_key, last_actor_id)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+ON CONFLICT (user_id, type, group_key) WHERE read_at IS NULL
+DO UPDATE SET updated_at = NOW(),
+    last_actor_id = EXCLUDED.last_actor_id
+RETURNING *;
+
+-- name: AddNotificationActor :exec
+INSERT INTO notification_actors (notification_id, actor_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: ListNotifications :many
+SELECT n.*,
+    (SELECT COUNT(*) FROM notification_actors a WHERE a.notification_id = n.id) AS actor_count
+FROM notifications n
+WHERE n.user_id = sqlc.arg(user_id)
+    AND (n.updated_at, n.id) < (sqlc.arg(cursor_time)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY n.updated_at DESC, n.id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: GetNotificationByID :one
+SELECT n.*,
+    (SELECT COUNT(*) FROM notification_actors a WHERE a.notification_id = n.id) AS actor_count
+FROM notifications n
+WHERE n.id = $1;
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: MarkNotificationRead :one
+UPDATE notifications
+SET read_at = COALESCE(read_at, NOW())
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: MarkAllNotificationsRead :execrows
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: GetNotificationPreferences :many
+SELECT * FROM notification_preferences WHERE user_id = $1 ORDER BY type ASC;
+
+-- name: UpsertNotificationPreference :one
+INSERT INTO notification_preferences (user_id, type, enabled, updated_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT (user_id, type)
+DO UPDATE SET enabled = EXCLUDED.enabled,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: NotificationEnabled :one
+SELECT COALESCE(
+    (SELECT enabled FROM notification_preferences WHERE user_id = $1 AND type = $2),
+    true
+)::boolean;
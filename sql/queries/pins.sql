@@ -0,0 +1,26 @@
+-- name: PinChirp :execrows
+INSERT INTO pinned_chirps (user_id, chirp_id, position, created_at)
+SELECT sqlc.arg(user_id)::uuid, sqlc.arg(chirp_id)::uuid, COALESCE(MAX(position) + 1, 0), NOW()
+FROM pinned_chirps
+WHERE user_id = sqlc.arg(user_id)
+ON CONFLICT DO NOTHING;
+
+-- name: UnpinChirp :execrows
+DELETE FROM pinned_chirps WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: CountPinnedChirps :one
+SELECT COUNT(*) FROM pinned_chirps p
+JOIN chirps c ON c.id = p.chirp_id
+WHERE p.user_id = $1 AND c.deleted_at IS NULL;
+
+-- name: GetPinnedChirpIDsByUserID :many
+SELECT p.chirp_id FROM pinned_chirps p
+JOIN chirps c ON c.id = p.chirp_id
+WHERE p.user_id = $1 AND c.deleted_at IS NULL
+ORDER BY p.position ASC;
+
+-- name: GetPinnedChirpIDs :many
+SELECT chirp_id FROM pinned_chirps WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+-- name: SetPinPosition :exec
+UPDATE pinned_chirps SET position = $3 WHERE user_id = $1 AND chirp_id = $2;
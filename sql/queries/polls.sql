@@ -0,0 +1,47 @@
+-- name: CreatePoll :one
+INSERT INTO polls (id, created_at, chirp_id, closes_at)
+VALUES (gen_random_uuid(), NOW(), $1, $2)
+RETURNING *;
+
+-- name: CreatePollOption :exec
+INSERT INTO poll_options (id, poll_id, position, label)
+VALUES (gen_random_uuid(), $1, $2, $3);
+
+-- name: GetPollByID :one
+SELECT * FROM polls WHERE id = $1;
+
+-- name: GetPollsByChirpIDs :many
+SELECT * FROM polls
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+-- name: GetPollOptionsWithVotes :many
+SELECT o.id, o.poll_id, o.position, o.label, COUNT(v.user_id) AS votes
+FROM poll_options o
+LEFT JOIN poll_votes v ON v.option_id = o.id
+WHERE o.poll_id = ANY(sqlc.arg(poll_ids)::uuid[])
+GROUP BY o.id
+ORDER BY o.poll_id, o.position ASC;
+
+-- name: GetUserPollVotes :many
+SELECT poll_id, option_id FROM poll_votes
+WHERE user_id = sqlc.arg(user_id) AND poll_id = ANY(sqlc.arg(poll_ids)::uuid[]);
+
+-- name: CreatePollVote :execrows
+INSERT INTO poll_votes (poll_id, user_id, option_id, created_at)
+SELECT p.id, sqlc.arg(user_id), sqlc.arg(option_id), NOW()
+FROM polls p
+WHERE p.id = sqlc.arg(poll_id) AND p.closes_at > (NOW() AT TIME ZONE 'UTC')
+ON CONFLICT DO NOTHING;
+
+-- name: ClaimEndedPolls :many
+UPDATE polls p
+SET ended_at = NOW()
+FROM chirps c
+WHERE c.id = p.chirp_id AND p.id IN (
+    SELECT id FROM polls
+    WHERE ended_at IS NULL AND closes_at <= (NOW() AT TIME ZONE 'UTC')
+    ORDER BY closes_at ASC
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING p.id, p.chirp_id, c.user_id;
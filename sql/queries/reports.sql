@@ -0,0 +1,58 @@
+-- name: CreateReport :one
+INSERT INTO reports (id, created_at, updated_at, reporter_id, target_type, chirp_id, user_id, chirp_body, reason, details, source)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
+)
+RETURNING *;
+
+-- name: GetReportByID :one
+SELECT * FROM reports WHERE id = $1;
+
+-- name: LockReport :one
+SELECT * FROM reports WHERE id = $1 FOR UPDATE;
+
+-- name: ListReports :many
+SELECT * FROM reports
+WHERE (sqlc.arg(status)::text = '' OR status = sqlc.arg(status))
+    AND (sqlc.arg(assignee_id)::uuid = '00000000-0000-0000-0000-000000000000' OR assignee_id = sqlc.arg(assignee_id))
+    AND (created_at, id) < (sqlc.arg(cursor_time)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: UpdateReport :one
+UPDATE reports
+SET status = $2,
+    assignee_id = $3,
+    resolution = $4,
+    resolved_at = CASE WHEN $2 IN ('resolved', 'dismissed') THEN NOW() END,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: CreateModerationAction :one
+INSERT INTO moderation_actions (id, created_at, report_id, moderator_id, action, from_status, to_status, assignee_id, note)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7
+)
+RETURNING *;
+
+-- name: GetModerationActionsByReportID :many
+SELECT * FROM moderation_actions WHERE report_id = $1 ORDER BY created_at ASC;
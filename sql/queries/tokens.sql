@@ -18,4 +18,10 @@ UPDATE refresh_tokens
 SET revoked_at = NOW(),
     updated_at = NOW()
 WHERE token = $1
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
\ No newline at end of file
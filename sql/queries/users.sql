@@ -1,12 +1,13 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password, is_chirpy_red)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
     $2,
-    $3
+    $3,
+    $4
 )
 RETURNING *;
 
@@ -14,7 +15,7 @@ RETURNING *;
 DELETE FROM users;
 
 -- name: GetUserByEmail :one
-SELECT * FROM users WHERE email = $1;
+SELECT * FROM users WHERE email = $1 AND deleted_at IS NULL;
 
 -- name: UpdateUser :one
 UPDATE users
@@ -29,4 +30,70 @@ UPDATE users
 SET updated_at = NOW(),
     is_chirpy_red = true
 WHERE id = $1
+RETURNING *;
+
+-- name: GetUserByID :one
+SELECT * FROM users WHERE id = $1;
+
+-- name: GetUserByHandle :one
+SELECT * FROM users WHERE LOWER(handle) = LOWER(sqlc.arg(handle)) AND deleted_at IS NULL;
+
+-- name: GetUsersByIDs :many
+SELECT * FROM users WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: GetUserIDsByHandles :many
+SELECT id FROM users WHERE LOWER(handle) = ANY(sqlc.arg(handles)::text[]) AND deleted_at IS NULL;
+
+-- name: UpdateUserProfile :one
+UPDATE users
+SET updated_at = NOW(),
+    handle = $2,
+    display_name = $3,
+    bio = $4,
+    location = $5,
+    avatar_url = $6
+WHERE id = $1
+RETURNING *;
+
+-- name: SoftDeleteUser :execrows
+UPDATE users
+SET deleted_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: GetDeletedUserByEmail :one
+SELECT * FROM users
+WHERE email = sqlc.arg(email)
+  AND deleted_at > NOW() - make_interval(days => sqlc.arg(retention_days)::int);
+
+-- name: RestoreUser :one
+UPDATE users
+SET deleted_at = NULL,
+    updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NOT NULL
+RETURNING *;
+
+-- name: PurgeDeletedUsers :many
+WITH purged AS (
+    DELETE FROM users
+    WHERE deleted_at <= NOW() - make_interval(days => sqlc.arg(retention_days)::int)
+    RETURNING id
+)
+SELECT purged.id, m.storage_key, m.thumbnail_key
+FROM purged
+LEFT JOIN media_attachments m ON m.user_id = purged.id;
+
+-- name: UpdateUserPreferences :one
+UPDATE users
+SET expand_sensitive_content = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetAccountState :one
+UPDATE users
+SET account_state = $2,
+    account_state_expires_at = $3,
+    account_state_reason = $4,
+    updated_at = NOW()
+WHERE id = $1
 RETURNING *;
\ No newline at end of file
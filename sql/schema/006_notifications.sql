@@ -0,0 +1,46 @@
+-- +goose Up
+-- Add notifications, coalesced per recipient, type and group while unread
+CREATE TABLE notifications (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    user_id UUID NOT NULL,
+    type TEXT NOT NULL,
+    chirp_id UUID,
+    group_key TEXT NOT NULL DEFAULT '',
+    last_actor_id UUID,
+    read_at TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (chirp_id) REFERENCES chirps (id) ON DELETE CASCADE,
+    FOREIGN KEY (last_actor_id) REFERENCES users (id) ON DELETE SET NULL
+);
+
+CREATE UNIQUE INDEX notifications_unread_group_idx
+ON notifications (user_id, type, group_key)
+WHERE read_at IS NULL;
+
+CREATE INDEX notifications_user_updated_at_idx
+ON notifications (user_id, updated_at DESC, id DESC);
+
+CREATE TABLE notification_actors (
+    notification_id UUID NOT NULL,
+    actor_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (notification_id, actor_id),
+    FOREIGN KEY (notification_id) REFERENCES notifications (id) ON DELETE CASCADE,
+    FOREIGN KEY (actor_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE notification_preferences (
+    user_id UUID NOT NULL,
+    type TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, type),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE notification_preferences;
+DROP TABLE notification_actors;
+DROP TABLE notifications;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- Add public profile fields to users. Handles are unique regardless of case.
+ALTER TABLE users
+ADD COLUMN handle TEXT CHECK (handle ~ '^[A-Za-z0-9_]{1,15}$'),
+ADD COLUMN display_name TEXT NOT NULL DEFAULT '',
+ADD COLUMN bio TEXT NOT NULL DEFAULT '',
+ADD COLUMN location TEXT NOT NULL DEFAULT '',
+ADD COLUMN avatar_url TEXT NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX users_handle_lower_idx ON users (LOWER(handle));
+
+-- +goose Down
+DROP INDEX users_handle_lower_idx;
+
+ALTER TABLE users
+DROP COLUMN avatar_url,
+DROP COLUMN location,
+DROP COLUMN bio,
+DROP COLUMN display_name,
+DROP COLUMN handle;
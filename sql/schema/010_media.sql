@@ -0,0 +1,22 @@
+-- +goose Up
+-- Add uploaded media. chirp_id is set once the media is attached to a chirp.
+CREATE TABLE media_attachments (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    user_id UUID NOT NULL,
+    content_type TEXT NOT NULL,
+    storage_key TEXT NOT NULL,
+    thumbnail_key TEXT NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    chirp_id UUID,
+    position INTEGER NOT NULL DEFAULT 0,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (chirp_id) REFERENCES chirps (id) ON DELETE CASCADE
+);
+
+CREATE INDEX media_attachments_chirp_idx ON media_attachments (chirp_id, position);
+
+-- +goose Down
+DROP TABLE media_attachments;
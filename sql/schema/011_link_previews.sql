@@ -0,0 +1,24 @@
+-- +goose Up
+-- Cached link metadata, shared by every chirp linking to the same URL.
+-- Failed fetches are recorded too so they aren't retried on every chirp.
+CREATE TABLE link_previews (
+    url TEXT PRIMARY KEY,
+    fetched_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    ok BOOLEAN NOT NULL,
+    title TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    image_url TEXT NOT NULL DEFAULT '',
+    site_name TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE chirp_links (
+    chirp_id UUID NOT NULL,
+    url TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    PRIMARY KEY (chirp_id, url),
+    FOREIGN KEY (chirp_id) REFERENCES chirps (id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE chirp_links;
+DROP TABLE link_previews;
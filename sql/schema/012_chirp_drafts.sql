@@ -0,0 +1,19 @@
+-- +goose Up
+-- Unpublished chirps. A draft with a publish_at time is scheduled; the row is
+-- deleted in the same transaction that publishes it.
+CREATE TABLE chirp_drafts (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    user_id UUID NOT NULL,
+    body TEXT NOT NULL,
+    media_ids UUID[] NOT NULL DEFAULT '{}',
+    publish_at TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX chirp_drafts_user_idx ON chirp_drafts (user_id, updated_at DESC);
+CREATE INDEX chirp_drafts_publish_at_idx ON chirp_drafts (publish_at) WHERE publish_at IS NOT NULL;
+
+-- +goose Down
+DROP TABLE chirp_drafts;
@@ -0,0 +1,42 @@
+-- +goose Up
+-- Polls attached to chirps. closes_at is stored in UTC. ended_at is set by
+-- the scheduler once the author has been told the poll is over.
+CREATE TABLE polls (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    chirp_id UUID NOT NULL UNIQUE,
+    closes_at TIMESTAMP NOT NULL,
+    ended_at TIMESTAMP,
+    FOREIGN KEY (chirp_id) REFERENCES chirps (id) ON DELETE CASCADE
+);
+
+CREATE INDEX polls_open_idx ON polls (closes_at) WHERE ended_at IS NULL;
+
+CREATE TABLE poll_options (
+    id UUID PRIMARY KEY,
+    poll_id UUID NOT NULL,
+    position INTEGER NOT NULL,
+    label TEXT NOT NULL,
+    UNIQUE (poll_id, position),
+    UNIQUE (id, poll_id),
+    FOREIGN KEY (poll_id) REFERENCES polls (id) ON DELETE CASCADE
+);
+
+-- The primary key allows one vote per user, and the composite foreign key
+-- ensures the option belongs to the poll being voted on.
+CREATE TABLE poll_votes (
+    poll_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    option_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (poll_id, user_id),
+    FOREIGN KEY (option_id, poll_id) REFERENCES poll_options (id, poll_id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX poll_votes_option_idx ON poll_votes (option_id);
+
+-- +goose Down
+DROP TABLE poll_votes;
+DROP TABLE poll_options;
+DROP TABLE polls;
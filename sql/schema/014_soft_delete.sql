@@ -0,0 +1,15 @@
+-- +goose Up
+-- Deleted chirps and accounts are kept for a retention window so they can be
+-- restored, then purged by a background job.
+ALTER TABLE chirps ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX chirps_deleted_at_idx ON chirps (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX users_deleted_at_idx ON users (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX users_deleted_at_idx;
+DROP INDEX chirps_deleted_at_idx;
+
+ALTER TABLE users DROP COLUMN deleted_at;
+ALTER TABLE chirps DROP COLUMN deleted_at;
@@ -0,0 +1,22 @@
+-- +goose Up
+-- Archives of a user's data, built in the background. expires_at is stored
+-- in UTC; expired archives are deleted.
+CREATE TABLE data_exports (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    user_id UUID NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'processing', 'ready', 'failed')),
+    storage_key TEXT NOT NULL DEFAULT '',
+    size_bytes BIGINT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- One export in progress per user.
+CREATE UNIQUE INDEX data_exports_in_progress_idx
+ON data_exports (user_id)
+WHERE status IN ('pending', 'processing');
+
+-- +goose Down
+DROP TABLE data_exports;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- Chirps pinned to the top of their author's profile, in the order given by
+-- position.
+CREATE TABLE pinned_chirps (
+    user_id UUID NOT NULL,
+    chirp_id UUID NOT NULL UNIQUE,
+    position INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, chirp_id),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (chirp_id) REFERENCES chirps (id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE pinned_chirps;
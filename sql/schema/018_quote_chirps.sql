@@ -0,0 +1,10 @@
+-- +goose Up
+-- A quote chirp stores the ID of the chirp it quotes. There is deliberately no
+-- foreign key: the quote outlives the original, which is then shown as
+-- unavailable.
+ALTER TABLE chirps ADD COLUMN quoted_chirp_id UUID;
+
+CREATE INDEX chirps_quoted_chirp_idx ON chirps (quoted_chirp_id) WHERE quoted_chirp_id IS NOT NULL;
+
+-- +goose Down
+ALTER TABLE chirps DROP COLUMN quoted_chirp_id;
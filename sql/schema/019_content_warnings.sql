@@ -0,0 +1,19 @@
+-- +goose Up
+-- Content warnings and sensitive media flags on chirps, set by their author or
+-- a moderator, and each user's choice of whether to expand such chirps.
+ALTER TABLE chirps
+    ADD COLUMN content_warning TEXT NOT NULL DEFAULT '',
+    ADD COLUMN sensitive_media BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE users
+    ADD COLUMN role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'moderator')),
+    ADD COLUMN expand_sensitive_content BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE users
+    DROP COLUMN expand_sensitive_content,
+    DROP COLUMN role;
+
+ALTER TABLE chirps
+    DROP COLUMN sensitive_media,
+    DROP COLUMN content_warning;
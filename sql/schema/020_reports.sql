@@ -0,0 +1,78 @@
+-- +goose Up
+-- Reports of abusive chirps and users, worked through by moderators.
+-- user_id is the reported user, or the author of the reported chirp.
+-- chirp_body keeps the reported text in case the chirp is later purged.
+CREATE TABLE reports (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    reporter_id UUID,
+    target_type TEXT NOT NULL CHECK (target_type IN ('chirp', 'user')),
+    chirp_id UUID,
+    user_id UUID,
+    chirp_body TEXT NOT NULL DEFAULT '',
+    reason TEXT NOT NULL CHECK (reason IN ('spam', 'harassment', 'hate', 'violence', 'sexual', 'self_harm', 'impersonation', 'other')),
+    details TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'in_review', 'resolved', 'dismissed')),
+    assignee_id UUID,
+    resolution TEXT CHECK (resolution IN ('dismiss', 'remove_chirp', 'suspend_user')),
+    resolved_at TIMESTAMP,
+    CHECK ((target_type = 'chirp') = (chirp_id IS NOT NULL)),
+    FOREIGN KEY (reporter_id) REFERENCES users (id) ON DELETE SET NULL,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL,
+    FOREIGN KEY (assignee_id) REFERENCES users (id) ON DELETE SET NULL
+);
+
+CREATE INDEX reports_status_idx ON reports (status, created_at DESC, id DESC);
+
+-- A user can have one unresolved report per chirp or user.
+CREATE UNIQUE INDEX reports_open_chirp_idx ON reports (reporter_id, chirp_id)
+WHERE target_type = 'chirp' AND status IN ('open', 'in_review');
+CREATE UNIQUE INDEX reports_open_user_idx ON reports (reporter_id, user_id)
+WHERE target_type = 'user' AND status IN ('open', 'in_review');
+
+-- Every moderator action on a report. Rows can never be changed or removed,
+-- and a report with actions can't be deleted. moderator_id has no foreign key
+-- so the record survives the moderator's account.
+CREATE TABLE moderation_actions (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    report_id UUID NOT NULL,
+    moderator_id UUID NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('assign', 'status', 'dismiss', 'remove_chirp', 'suspend_user')),
+    from_status TEXT NOT NULL,
+    to_status TEXT NOT NULL,
+    assignee_id UUID,
+    note TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (report_id) REFERENCES reports (id)
+);
+
+CREATE INDEX moderation_actions_report_idx ON moderation_actions (report_id, created_at);
+
+-- +goose StatementBegin
+CREATE FUNCTION moderation_actions_immutable() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'moderation_actions is append-only';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER moderation_actions_no_update
+BEFORE UPDATE OR DELETE ON moderation_actions
+FOR EACH ROW EXECUTE FUNCTION moderation_actions_immutable();
+
+CREATE TRIGGER moderation_actions_no_truncate
+BEFORE TRUNCATE ON moderation_actions
+FOR EACH STATEMENT EXECUTE FUNCTION moderation_actions_immutable();
+
+-- Chirps removed by a moderator are deleted and can't be restored by their
+-- author. Suspended users can't log in.
+ALTER TABLE chirps ADD COLUMN removed_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN suspended_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN suspended_at;
+ALTER TABLE chirps DROP COLUMN removed_at;
+DROP TABLE moderation_actions;
+DROP FUNCTION moderation_actions_immutable();
+DROP TABLE reports;
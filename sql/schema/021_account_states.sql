@@ -0,0 +1,24 @@
+-- +goose Up
+-- Replaces suspended_at with an account state. A state with an expiry (stored
+-- in UTC) lapses back to active once it passes.
+ALTER TABLE users
+    ADD COLUMN account_state TEXT NOT NULL DEFAULT 'active'
+        CHECK (account_state IN ('active', 'limited', 'shadowbanned', 'suspended')),
+    ADD COLUMN account_state_expires_at TIMESTAMP,
+    ADD COLUMN account_state_reason TEXT NOT NULL DEFAULT '';
+
+UPDATE users SET account_state = 'suspended' WHERE suspended_at IS NOT NULL;
+
+ALTER TABLE users DROP COLUMN suspended_at;
+
+-- +goose Down
+ALTER TABLE users ADD COLUMN suspended_at TIMESTAMP;
+
+UPDATE users SET suspended_at = NOW()
+WHERE account_state = 'suspended'
+    AND (account_state_expires_at IS NULL OR account_state_expires_at > (NOW() AT TIME ZONE 'UTC'));
+
+ALTER TABLE users
+    DROP COLUMN account_state_reason,
+    DROP COLUMN account_state_expires_at,
+    DROP COLUMN account_state;
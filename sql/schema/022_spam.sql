@@ -0,0 +1,21 @@
+-- +goose Up
+-- body_hash is the hash of a chirp's normalized body, used to spot repeats.
+-- Chirps flagged as likely spam are held: 'delay' chirps are published once
+-- held_until (UTC) passes, 'review' chirps wait for a moderator. Held chirps
+-- are only visible to their author.
+ALTER TABLE chirps
+    ADD COLUMN body_hash TEXT NOT NULL DEFAULT '',
+    ADD COLUMN hold TEXT NOT NULL DEFAULT '' CHECK (hold IN ('', 'delay', 'review')),
+    ADD COLUMN held_until TIMESTAMP,
+    ADD CHECK ((hold = 'delay') = (held_until IS NOT NULL));
+
+CREATE INDEX chirps_user_body_hash_idx ON chirps (user_id, body_hash, created_at);
+CREATE INDEX chirps_held_until_idx ON chirps (held_until) WHERE hold = 'delay';
+
+-- +goose Down
+DROP INDEX chirps_held_until_idx;
+DROP INDEX chirps_user_body_hash_idx;
+ALTER TABLE chirps
+    DROP COLUMN held_until,
+    DROP COLUMN hold,
+    DROP COLUMN body_hash;
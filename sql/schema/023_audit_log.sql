@@ -0,0 +1,50 @@
+-- +goose Up
+-- Security-sensitive events: sign-ins, token use, account changes and admin
+-- and moderator actions. Like moderation_actions, rows can never be changed
+-- or removed, and actor_id and target_id have no foreign keys so the record
+-- survives the accounts involved.
+CREATE TABLE audit_log (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    action TEXT NOT NULL,
+    outcome TEXT NOT NULL CHECK (outcome IN ('success', 'failure')),
+    actor_id UUID,
+    target_type TEXT NOT NULL DEFAULT '',
+    target_id UUID,
+    ip TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    request_id TEXT NOT NULL DEFAULT '',
+    details TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX audit_log_created_idx ON audit_log (created_at DESC, id DESC);
+CREATE INDEX audit_log_actor_idx ON audit_log (actor_id, created_at DESC);
+CREATE INDEX audit_log_target_idx ON audit_log (target_id, created_at DESC);
+
+-- +goose StatementBegin
+CREATE FUNCTION audit_log_immutable() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER audit_log_no_update
+BEFORE UPDATE OR DELETE ON audit_log
+FOR EACH ROW EXECUTE FUNCTION audit_log_immutable();
+
+CREATE TRIGGER audit_log_no_truncate
+BEFORE TRUNCATE ON audit_log
+FOR EACH STATEMENT EXECUTE FUNCTION audit_log_immutable();
+
+-- Admins can read the audit log, and can do everything moderators can.
+ALTER TABLE users DROP CONSTRAINT users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('user', 'moderator', 'admin'));
+
+-- +goose Down
+UPDATE users SET role = 'moderator' WHERE role = 'admin';
+ALTER TABLE users DROP CONSTRAINT users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('user', 'moderator'));
+
+DROP TABLE audit_log;
+DROP FUNCTION audit_log_immutable();
@@ -0,0 +1,11 @@
+-- +goose Up
+-- A scheduled chirp's poll, created when the chirp is published. An empty
+-- option list means the draft has no poll.
+ALTER TABLE chirp_drafts
+    ADD COLUMN poll_options TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN poll_closes_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE chirp_drafts
+    DROP COLUMN poll_closes_at,
+    DROP COLUMN poll_options;
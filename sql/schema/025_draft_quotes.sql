@@ -0,0 +1,8 @@
+-- +goose Up
+-- The chirp a scheduled chirp quotes. Like chirps.quoted_chirp_id it has no
+-- foreign key; the scheduler checks the original is still quotable when it
+-- publishes the chirp.
+ALTER TABLE chirp_drafts ADD COLUMN quoted_chirp_id UUID;
+
+-- +goose Down
+ALTER TABLE chirp_drafts DROP COLUMN quoted_chirp_id;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- A scheduled chirp's content warning and sensitive media flag, copied to the
+-- chirp when it is published.
+ALTER TABLE chirp_drafts
+    ADD COLUMN content_warning TEXT NOT NULL DEFAULT '',
+    ADD COLUMN sensitive_media BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE chirp_drafts
+    DROP COLUMN sensitive_media,
+    DROP COLUMN content_warning;
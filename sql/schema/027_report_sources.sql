@@ -0,0 +1,11 @@
+-- +goose Up
+-- Who filed a report. Spam filter reports have no reporter, but neither does
+-- a user's report once the reporter deletes their account.
+ALTER TABLE reports
+    ADD COLUMN source TEXT NOT NULL DEFAULT 'user' CHECK (source IN ('user', 'spam_filter'));
+UPDATE reports SET source = 'spam_filter'
+WHERE reporter_id IS NULL AND reason = 'spam' AND details LIKE 'Held by the spam filter%';
+ALTER TABLE reports ALTER COLUMN source DROP DEFAULT;
+
+-- +goose Down
+ALTER TABLE reports DROP COLUMN source;